@@ -1,36 +1,147 @@
 package cgroups
 
-import "minidocker/cgroups/subsystems"
+import (
+	"fmt"
+	"minidocker/cgroups/subsystems"
+	"path"
+)
 
-type CgroupManager struct {
+type CgroupManager interface {
+	Apply(pid int) error
+	Set(res *subsystems.ResourceConfig) error
+	Destroy() error
+	OOMKillCount() (uint64, error)
+	Freeze(state string) error
+	GetPaths() map[string]string
+	GetPids() ([]int, error)
+	GetStats() (*Stats, error)
+}
+
+// unlimitedMemory is the page aligned max int64 v1 reports without a limit.
+const unlimitedMemory = 0x7FFFFFFFFFFFF000
+
+// NewCgroupManager picks the manager matching the hierarchy the host booted with.
+func NewCgroupManager(path string) CgroupManager {
+	if subsystems.IsCgroup2UnifiedMode() {
+		return NewCgroupV2Manager(path)
+	}
+	return NewCgroupV1Manager(path)
+}
+
+type CgroupV1Manager struct {
 	Path     string
 	Resource *subsystems.ResourceConfig
 }
 
-func NewCgroupManager(path string) *CgroupManager {
-	return &CgroupManager{
+func NewCgroupV1Manager(path string) *CgroupV1Manager {
+	return &CgroupV1Manager{
 		Path: path,
 	}
 }
 
-func (c *CgroupManager) Apply(pid int) error {
-	for _, subSys := range subsystems.Subsystems {
-		_ = subSys.Apply(c.Path, pid)
+// Apply moves pid into every mounted subsystem and reports the first failure.
+func (c *CgroupV1Manager) Apply(pid int) error {
+	var result error
+	for _, subSys := range mountedSubsystems() {
+		if err := subSys.Apply(c.Path, pid); err != nil && result == nil {
+			result = err
+		}
 	}
-	return nil
+	return result
 }
 
-func (c *CgroupManager) Set(res *subsystems.ResourceConfig) error {
+// Set configures every mounted subsystem and reports the first failure, the
+// others are still applied. A hierarchy the host lacks is only an error when
+// res asks for what it enforces.
+func (c *CgroupV1Manager) Set(res *subsystems.ResourceConfig) error {
+	var result error
 	for _, subSys := range subsystems.Subsystems {
-		_ = subSys.Set(c.Path, res)
+		if subsystems.FindCgroupMountPoint(subSys.Name()) == "" {
+			if unified, ok := subSys.(subsystems.UnifiedSubsystem); ok && unified.Requested(res) && result == nil {
+				result = fmt.Errorf("cgroup subsystem %s not mounted", subSys.Name())
+			}
+			continue
+		}
+		if err := subSys.Set(c.Path, res); err != nil && result == nil {
+			result = err
+		}
 	}
-	return nil
+	return result
 }
-func (c *CgroupManager) Destroy() error {
-	for _, subSys := range subsystems.Subsystems {
+
+func (c *CgroupV1Manager) Destroy() error {
+	for _, subSys := range mountedSubsystems() {
 		if err := subSys.Remove(c.Path); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+func (c *CgroupV1Manager) OOMKillCount() (uint64, error) {
+	return subsystems.ReadOOMKillCount(c.Path)
+}
+
+// Freeze to Thawed is a no-op without a freezer hierarchy, nothing can be
+// frozen then.
+func (c *CgroupV1Manager) Freeze(state string) error {
+	if state == subsystems.Thawed && subsystems.FindCgroupMountPoint(subsystems.Freezer.Name()) == "" {
+		return nil
+	}
+	return subsystems.Freezer.Freeze(c.Path, state)
+}
+
+func (c *CgroupV1Manager) GetPaths() map[string]string {
+	paths := make(map[string]string)
+	for _, subSys := range subsystems.Subsystems {
+		if mountPoint := subsystems.FindCgroupMountPoint(subSys.Name()); mountPoint != "" {
+			paths[subSys.Name()] = path.Join(mountPoint, c.Path)
+		}
+	}
+	return paths
+}
+
+// GetPids reads the member processes from the first mounted hierarchy, every
+// v1 subsystem holds the same set.
+func (c *CgroupV1Manager) GetPids() ([]int, error) {
+	for _, subSys := range subsystems.Subsystems {
+		if subsystems.FindCgroupMountPoint(subSys.Name()) == "" {
+			continue
+		}
+		cgroupPath, err := subsystems.GetCgroupPath(subSys.Name(), c.Path, false)
+		if err != nil {
+			return nil, err
+		}
+		return subsystems.ReadCgroupProcs(cgroupPath)
+	}
+	return nil, fmt.Errorf("no cgroup hierarchy mounted")
+}
+
+func (c *CgroupV1Manager) GetStats() (*Stats, error) {
+	pids, err := c.GetPids()
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{Pids: uint64(len(pids))}
+	if current, err := subsystems.ReadPidsCurrent(c.Path); err == nil {
+		stats.Pids = current
+	}
+	if stats.MemoryUsage, stats.MemoryLimit, err = subsystems.ReadMemoryStats(c.Path); err == nil && stats.MemoryLimit >= unlimitedMemory {
+		stats.MemoryLimit = 0
+	}
+	stats.CpuUsage, _ = subsystems.ReadCpuUsage(c.Path)
+	stats.BlockRead, stats.BlockWrite, _ = subsystems.ReadBlkioStats(c.Path)
+	return stats, nil
+}
+
+// mountedSubsystems leaves out the hierarchies the host does not mount, a
+// v1 host is not required to provide every controller.
+func mountedSubsystems() []subsystems.Subsystem {
+	var mounted []subsystems.Subsystem
+	for _, subSys := range subsystems.Subsystems {
+		if subsystems.FindCgroupMountPoint(subSys.Name()) != "" {
+			mounted = append(mounted, subSys)
+		}
+	}
+	return mounted
+}
@@ -0,0 +1,117 @@
+package cgroups
+
+import (
+	"fmt"
+	"io/ioutil"
+	"minidocker/cgroups/subsystems"
+	"os"
+	"path"
+	"strconv"
+)
+
+// CgroupV2Manager drives a single directory of the unified hierarchy, every
+// controller shares it so processes are attached and removed only once.
+type CgroupV2Manager struct {
+	Path     string
+	Resource *subsystems.ResourceConfig
+}
+
+func NewCgroupV2Manager(path string) *CgroupV2Manager {
+	return &CgroupV2Manager{
+		Path: path,
+	}
+}
+
+func (c *CgroupV2Manager) Apply(pid int) error {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, true)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(cgroupPath, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("set cgroup proc fail %v", err)
+	}
+	return nil
+}
+
+func (c *CgroupV2Manager) Set(res *subsystems.ResourceConfig) error {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, true)
+	if err != nil {
+		return err
+	}
+	var result error
+	for _, subSys := range subsystems.Subsystems {
+		unified, ok := subSys.(subsystems.UnifiedSubsystem)
+		if !ok {
+			continue
+		}
+		if err := subsystems.EnableUnifiedController(c.Path, unified.Controller()); err != nil {
+			// a controller the host does not delegate only matters when asked for
+			if unified.Requested(res) && result == nil {
+				result = err
+			}
+			continue
+		}
+		if err := unified.SetUnified(cgroupPath, res); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (c *CgroupV2Manager) Destroy() error {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, false)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(cgroupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *CgroupV2Manager) OOMKillCount() (uint64, error) {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, false)
+	if err != nil {
+		return 0, err
+	}
+	return subsystems.ReadUnifiedOOMKillCount(cgroupPath)
+}
+
+func (c *CgroupV2Manager) Freeze(state string) error {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, false)
+	if err != nil {
+		return err
+	}
+	return subsystems.Freezer.FreezeUnified(cgroupPath, state)
+}
+
+func (c *CgroupV2Manager) GetPaths() map[string]string {
+	return map[string]string{"unified": path.Join(subsystems.UnifiedMountPoint, c.Path)}
+}
+
+func (c *CgroupV2Manager) GetPids() ([]int, error) {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, false)
+	if err != nil {
+		return nil, err
+	}
+	return subsystems.ReadCgroupProcs(cgroupPath)
+}
+
+func (c *CgroupV2Manager) GetStats() (*Stats, error) {
+	cgroupPath, err := subsystems.GetUnifiedCgroupPath(c.Path, false)
+	if err != nil {
+		return nil, err
+	}
+	pids, err := subsystems.ReadCgroupProcs(cgroupPath)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{Pids: uint64(len(pids))}
+	if current, err := subsystems.ReadUnifiedPidsCurrent(cgroupPath); err == nil {
+		stats.Pids = current
+	}
+	stats.MemoryUsage, stats.MemoryLimit, _ = subsystems.ReadUnifiedMemoryStats(cgroupPath)
+	stats.CpuUsage, _ = subsystems.ReadUnifiedCpuUsage(cgroupPath)
+	stats.BlockRead, stats.BlockWrite, _ = subsystems.ReadUnifiedIOStats(cgroupPath)
+	return stats, nil
+}
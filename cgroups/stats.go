@@ -0,0 +1,15 @@
+package cgroups
+
+// Stats is a snapshot of the resource counters of one cgroup, counters
+// missing on the host are left at zero.
+type Stats struct {
+	// MemoryUsage excludes the inactive file cache, MemoryLimit is 0 when unlimited
+	MemoryUsage uint64
+	MemoryLimit uint64
+	// CpuUsage is the total cpu time in nanoseconds
+	CpuUsage uint64
+	// Pids is pids.current, the number of member processes without the controller
+	Pids       uint64
+	BlockRead  uint64
+	BlockWrite uint64
+}
@@ -0,0 +1,267 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	minBlkioWeight = 10
+	maxBlkioWeight = 1000
+)
+
+// ThrottleDevice limits the rate of one block device, in bytes or
+// operations per second, a rate of 0 removes the limit.
+type ThrottleDevice struct {
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+	Rate  uint64 `json:"rate"`
+}
+
+func (d ThrottleDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", d.Major, d.Minor, d.Rate)
+}
+
+type BlkioSubsystem struct {
+}
+
+func (s *BlkioSubsystem) Name() string {
+	return "blkio"
+}
+
+func (s *BlkioSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		if res.BlkioWeight != "" {
+			if err := writeFirst(subsysCgroupPath, []string{"blkio.weight", "blkio.bfq.weight"}, res.BlkioWeight); err != nil {
+				return fmt.Errorf("set cgroup blkio weight fail %v", err)
+			}
+		}
+		throttles := map[string][]ThrottleDevice{
+			"blkio.throttle.read_bps_device":   res.DeviceReadBps,
+			"blkio.throttle.write_bps_device":  res.DeviceWriteBps,
+			"blkio.throttle.read_iops_device":  res.DeviceReadIOps,
+			"blkio.throttle.write_iops_device": res.DeviceWriteIOps,
+		}
+		for file, devices := range throttles {
+			// the kernel takes one device per write
+			for _, device := range devices {
+				if err := ioutil.WriteFile(path.Join(subsysCgroupPath, file), []byte(device.String()), 0644); err != nil {
+					return fmt.Errorf("set cgroup %s fail %v", file, err)
+				}
+			}
+		}
+	} else {
+		return err
+	}
+	return nil
+}
+
+func (s *BlkioSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (s *BlkioSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.RemoveAll(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *BlkioSubsystem) Controller() string {
+	return "io"
+}
+
+func (s *BlkioSubsystem) Requested(res *ResourceConfig) bool {
+	return res.BlkioWeight != "" || len(res.DeviceReadBps) > 0 || len(res.DeviceWriteBps) > 0 ||
+		len(res.DeviceReadIOps) > 0 || len(res.DeviceWriteIOps) > 0
+}
+
+// SetUnified writes io.weight and one io.max line per throttled device
+// holding all of its limits, a rate of 0 is written as max.
+func (s *BlkioSubsystem) SetUnified(cgroupPath string, res *ResourceConfig) error {
+	if res.BlkioWeight != "" {
+		weight, err := strconv.ParseUint(res.BlkioWeight, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse blkio weight %s fail %v", res.BlkioWeight, err)
+		}
+		// io.bfq.weight keeps the v1 range, only io.weight needs converting
+		if _, err = os.Stat(path.Join(cgroupPath, "io.weight")); err == nil {
+			value := "default " + strconv.FormatUint(convertBlkioToIOWeight(weight), 10)
+			err = ioutil.WriteFile(path.Join(cgroupPath, "io.weight"), []byte(value), 0644)
+		} else {
+			err = writeFirst(cgroupPath, []string{"io.bfq.weight"}, res.BlkioWeight)
+		}
+		if err != nil {
+			return fmt.Errorf("set cgroup io weight fail %v", err)
+		}
+	}
+
+	var order []string
+	limits := make(map[string][]string)
+	add := func(devices []ThrottleDevice, key string) {
+		for _, device := range devices {
+			id := fmt.Sprintf("%d:%d", device.Major, device.Minor)
+			if _, ok := limits[id]; !ok {
+				order = append(order, id)
+			}
+			rate := "max"
+			if device.Rate > 0 {
+				rate = strconv.FormatUint(device.Rate, 10)
+			}
+			limits[id] = append(limits[id], key+"="+rate)
+		}
+	}
+	add(res.DeviceReadBps, "rbps")
+	add(res.DeviceWriteBps, "wbps")
+	add(res.DeviceReadIOps, "riops")
+	add(res.DeviceWriteIOps, "wiops")
+	for _, id := range order {
+		line := id + " " + strings.Join(limits[id], " ")
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "io.max"), []byte(line), 0644); err != nil {
+			return fmt.Errorf("set cgroup io max fail %v", err)
+		}
+	}
+	return nil
+}
+
+// convertBlkioToIOWeight maps the v1 weight range [10, 1000] onto the v2
+// range [1, 10000].
+func convertBlkioToIOWeight(weight uint64) uint64 {
+	return 1 + (weight-minBlkioWeight)*9999/(maxBlkioWeight-minBlkioWeight)
+}
+
+// writeFirst writes value to the first of files present in dir, the weight
+// lives in a different file depending on the io scheduler.
+func writeFirst(dir string, files []string, value string) error {
+	for _, file := range files {
+		if _, err := os.Stat(path.Join(dir, file)); err != nil {
+			continue
+		}
+		return ioutil.WriteFile(path.Join(dir, file), []byte(value), 0644)
+	}
+	return fmt.Errorf("none of %s is supported", strings.Join(files, ", "))
+}
+
+// ValidateBlkioWeight checks the weight against the range the kernel accepts.
+func ValidateBlkioWeight(weight string) error {
+	value, err := strconv.ParseUint(weight, 10, 64)
+	if err != nil || value < minBlkioWeight || value > maxBlkioWeight {
+		return fmt.Errorf("invalid blkio weight %s, must be between %d and %d", weight, minBlkioWeight, maxBlkioWeight)
+	}
+	return nil
+}
+
+// ParseThrottleDevice parses "/dev/sda:rate" into the device numbers of the
+// block device and its rate, sizes like 10mb are accepted when bytes is set.
+func ParseThrottleDevice(spec string, bytes bool) (ThrottleDevice, error) {
+	index := strings.LastIndex(spec, ":")
+	if index <= 0 {
+		return ThrottleDevice{}, fmt.Errorf("invalid device limit %s, expected <device-path>:<rate>", spec)
+	}
+	devicePath, rateSpec := spec[:index], spec[index+1:]
+
+	var rate int64
+	var err error
+	if bytes {
+		rate, err = ParseSize(rateSpec)
+	} else {
+		rate, err = strconv.ParseInt(rateSpec, 10, 64)
+	}
+	if err != nil || rate < 0 {
+		return ThrottleDevice{}, fmt.Errorf("invalid rate %s of device %s", rateSpec, devicePath)
+	}
+
+	var stat syscall.Stat_t
+	if err = syscall.Stat(devicePath, &stat); err != nil {
+		return ThrottleDevice{}, fmt.Errorf("stat device %s fail %v", devicePath, err)
+	}
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return ThrottleDevice{}, fmt.Errorf("%s is not a block device", devicePath)
+	}
+	return ThrottleDevice{
+		Major: int64(deviceMajor(stat.Rdev)),
+		Minor: int64(deviceMinor(stat.Rdev)),
+		Rate:  uint64(rate),
+	}, nil
+}
+
+// deviceMajor and deviceMinor decode the glibc dev_t layout.
+func deviceMajor(dev uint64) uint64 {
+	return ((dev >> 8) & 0xfff) | ((dev >> 32) &^ 0xfff)
+}
+
+func deviceMinor(dev uint64) uint64 {
+	return (dev & 0xff) | ((dev >> 12) &^ 0xff)
+}
+
+// ReadBlkioStats sums the bytes read and written over all devices from
+// blkio.throttle.io_service_bytes, lines look like "8:0 Read 4096".
+func ReadBlkioStats(cgroupPath string) (uint64, uint64, error) {
+	subsysCgroupPath, err := GetCgroupPath("blkio", cgroupPath, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	content, err := ioutil.ReadFile(path.Join(subsysCgroupPath, "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var read, write uint64
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += value
+		case "Write":
+			write += value
+		}
+	}
+	return read, write, nil
+}
+
+// ReadUnifiedIOStats sums rbytes and wbytes over all devices of io.stat.
+func ReadUnifiedIOStats(cgroupPath string) (uint64, uint64, error) {
+	content, err := ioutil.ReadFile(path.Join(cgroupPath, "io.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var read, write uint64
+	for _, line := range strings.Split(string(content), "\n") {
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			number, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				read += number
+			case "wbytes":
+				write += number
+			}
+		}
+	}
+	return read, write, nil
+}
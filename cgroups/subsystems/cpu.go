@@ -5,7 +5,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"runtime"
 	"strconv"
+	"strings"
+)
+
+const (
+	DefaultCpuPeriod = 100000
+	minCpuPeriod     = 1000
+	maxCpuPeriod     = 1000000
+	minCpuQuota      = 1000
 )
 
 type CpuSubsystem struct {
@@ -17,11 +26,21 @@ func (s *CpuSubsystem) Name() string {
 
 func (s *CpuSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
-		if res.CpuSet != "" {
+		if res.CpuShare != "" {
 			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "cpu.shares"), []byte(res.CpuShare), 0644); err != nil {
 				return fmt.Errorf("set cgroup cpu share fail %v", err)
 			}
-			return nil
+		}
+		// the period goes first, the kernel checks the quota against it
+		if res.CpuPeriod != "" {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "cpu.cfs_period_us"), []byte(res.CpuPeriod), 0644); err != nil {
+				return fmt.Errorf("set cgroup cpu period fail %v", err)
+			}
+		}
+		if res.CpuQuota != "" {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "cpu.cfs_quota_us"), []byte(res.CpuQuota), 0644); err != nil {
+				return fmt.Errorf("set cgroup cpu quota fail %v", err)
+			}
 		}
 	} else {
 		return err
@@ -38,7 +57,6 @@ func (s *CpuSubsystem) Apply(cgroupPath string, pid int) error {
 	} else {
 		return err
 	}
-	return nil
 }
 
 func (s *CpuSubsystem) Remove(cgroupPath string) error {
@@ -47,5 +65,101 @@ func (s *CpuSubsystem) Remove(cgroupPath string) error {
 	} else {
 		return err
 	}
+}
+
+func (s *CpuSubsystem) Controller() string {
+	return "cpu"
+}
+
+func (s *CpuSubsystem) Requested(res *ResourceConfig) bool {
+	return res.CpuShare != "" || res.CpuQuota != "" || res.CpuPeriod != ""
+}
+
+func (s *CpuSubsystem) SetUnified(cgroupPath string, res *ResourceConfig) error {
+	if res.CpuShare != "" {
+		shares, err := strconv.ParseUint(res.CpuShare, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse cpu share %s fail %v", res.CpuShare, err)
+		}
+		weight := strconv.FormatUint(convertCpuSharesToWeight(shares), 10)
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "cpu.weight"), []byte(weight), 0644); err != nil {
+			return fmt.Errorf("set cgroup cpu weight fail %v", err)
+		}
+	}
+	if res.CpuQuota != "" || res.CpuPeriod != "" {
+		if err := setUnifiedCpuMax(cgroupPath, res); err != nil {
+			return fmt.Errorf("set cgroup cpu max fail %v", err)
+		}
+	}
+	return nil
+}
+
+// setUnifiedCpuMax writes "$QUOTA $PERIOD" to cpu.max, the half that is not
+// being changed is taken from the current value.
+func setUnifiedCpuMax(cgroupPath string, res *ResourceConfig) error {
+	quota, period := "max", strconv.Itoa(DefaultCpuPeriod)
+	if content, err := ioutil.ReadFile(path.Join(cgroupPath, "cpu.max")); err == nil {
+		if fields := strings.Fields(string(content)); len(fields) == 2 {
+			quota, period = fields[0], fields[1]
+		}
+	}
+	if res.CpuQuota != "" {
+		quota = res.CpuQuota
+		if quota == "-1" {
+			quota = "max"
+		}
+	}
+	if res.CpuPeriod != "" {
+		period = res.CpuPeriod
+	}
+	return ioutil.WriteFile(path.Join(cgroupPath, "cpu.max"), []byte(quota+" "+period), 0644)
+}
+
+// convertCpuSharesToWeight maps the v1 shares range [2, 262144] onto the
+// v2 weight range [1, 10000].
+func convertCpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	} else if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// ReadUnifiedCpuUsage returns usage_usec of cpu.stat in nanoseconds.
+func ReadUnifiedCpuUsage(cgroupPath string) (uint64, error) {
+	usage, err := readKeyedValue(path.Join(cgroupPath, "cpu.stat"), "usage_usec")
+	if err != nil {
+		return 0, err
+	}
+	return usage * 1000, nil
+}
+
+// CpusToQuota turns a number of cpus like 1.5 into a quota for the default period.
+func CpusToQuota(cpus float64) (string, string, error) {
+	if cpus <= 0 {
+		return "", "", fmt.Errorf("invalid cpus %v, must be greater than 0", cpus)
+	}
+	if online := runtime.NumCPU(); cpus > float64(online) {
+		return "", "", fmt.Errorf("invalid cpus %v, only %d cpus are available", cpus, online)
+	}
+	quota := int64(cpus * DefaultCpuPeriod)
+	return strconv.FormatInt(quota, 10), strconv.Itoa(DefaultCpuPeriod), nil
+}
+
+// ValidateCpuQuota checks the period and quota against the range the kernel accepts.
+func ValidateCpuQuota(res *ResourceConfig) error {
+	if res.CpuPeriod != "" {
+		period, err := strconv.ParseInt(res.CpuPeriod, 10, 64)
+		if err != nil || period < minCpuPeriod || period > maxCpuPeriod {
+			return fmt.Errorf("invalid cpu period %s, must be between %d and %d microseconds", res.CpuPeriod, minCpuPeriod, maxCpuPeriod)
+		}
+	}
+	if res.CpuQuota != "" {
+		quota, err := strconv.ParseInt(res.CpuQuota, 10, 64)
+		if err != nil || (quota != -1 && quota < minCpuQuota) {
+			return fmt.Errorf("invalid cpu quota %s, must be -1 or at least %d microseconds", res.CpuQuota, minCpuQuota)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CpuacctSubsystem only accounts, the container joins it so its cpu time can
+// be read when cpuacct is not mounted together with cpu.
+type CpuacctSubsystem struct {
+}
+
+func (s *CpuacctSubsystem) Name() string {
+	return "cpuacct"
+}
+
+func (s *CpuacctSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	_, err := GetCgroupPath(s.Name(), cgroupPath, true)
+	return err
+}
+
+func (s *CpuacctSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+// Remove tolerates a missing directory, with cpu,cpuacct mounted together
+// the cpu subsystem has already removed it.
+func (s *CpuacctSubsystem) Remove(cgroupPath string) error {
+	mountPoint := FindCgroupMountPoint(s.Name())
+	if mountPoint == "" {
+		return nil
+	}
+	subsysCgroupPath := path.Join(mountPoint, cgroupPath)
+	if err := os.RemoveAll(subsysCgroupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ReadCpuUsage returns the total cpu time of the cgroup in nanoseconds.
+func ReadCpuUsage(cgroupPath string) (uint64, error) {
+	subsysCgroupPath, err := GetCgroupPath("cpuacct", cgroupPath, false)
+	if err != nil {
+		return 0, err
+	}
+	content, err := ioutil.ReadFile(path.Join(subsysCgroupPath, "cpuacct.usage"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
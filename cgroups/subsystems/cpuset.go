@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 )
 
 type CpusetSubsystem struct {
@@ -17,7 +18,10 @@ func (s *CpusetSubsystem) Name() string {
 
 func (s *CpusetSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
-		if res.MemoryLimit != "" {
+		if err := initCpuset(subsysCgroupPath); err != nil {
+			return fmt.Errorf("init cgroup cpuset fail %v", err)
+		}
+		if res.CpuSet != "" {
 			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "cpuset.cpus"), []byte(res.CpuSet), 0644); err != nil {
 				return fmt.Errorf("set cgroup cpuset fail %v", err)
 			}
@@ -29,6 +33,32 @@ func (s *CpusetSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	return nil
 }
 
+// initCpuset copies cpuset.cpus and cpuset.mems down from the parent, a
+// nested cpuset cgroup refuses tasks until both of them are populated.
+func initCpuset(subsysCgroupPath string) error {
+	parent := path.Dir(subsysCgroupPath)
+	for _, file := range []string{"cpuset.cpus", "cpuset.mems"} {
+		current, err := ioutil.ReadFile(path.Join(subsysCgroupPath, file))
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(current)) != "" {
+			continue
+		}
+		if err := initCpuset(parent); err != nil {
+			return err
+		}
+		value, err := ioutil.ReadFile(path.Join(parent, file))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, file), value, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *CpusetSubsystem) Apply(cgroupPath string, pid int) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
@@ -38,7 +68,6 @@ func (s *CpusetSubsystem) Apply(cgroupPath string, pid int) error {
 	} else {
 		return err
 	}
-	return nil
 }
 
 func (s *CpusetSubsystem) Remove(cgroupPath string) error {
@@ -47,5 +76,21 @@ func (s *CpusetSubsystem) Remove(cgroupPath string) error {
 	} else {
 		return err
 	}
+}
+
+func (s *CpusetSubsystem) Controller() string {
+	return "cpuset"
+}
+
+func (s *CpusetSubsystem) Requested(res *ResourceConfig) bool {
+	return res.CpuSet != ""
+}
+
+func (s *CpusetSubsystem) SetUnified(cgroupPath string, res *ResourceConfig) error {
+	if res.CpuSet != "" {
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "cpuset.cpus"), []byte(res.CpuSet), 0644); err != nil {
+			return fmt.Errorf("set cgroup cpuset fail %v", err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,98 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Frozen = "FROZEN"
+	Thawed = "THAWED"
+
+	freezeTimeout = 10 * time.Second
+)
+
+type FreezerSubsystem struct {
+}
+
+func (s *FreezerSubsystem) Name() string {
+	return "freezer"
+}
+
+// Set has nothing to configure, the freezer is only driven by Freeze.
+func (s *FreezerSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	_, err := GetCgroupPath(s.Name(), cgroupPath, true)
+	return err
+}
+
+func (s *FreezerSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (s *FreezerSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.RemoveAll(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+// Freeze writes state to freezer.state and waits for the kernel to finish
+// the transition, FREEZING is reported while tasks are still running.
+func (s *FreezerSubsystem) Freeze(cgroupPath string, state string) error {
+	subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	stateFile := path.Join(subsysCgroupPath, "freezer.state")
+	if err := ioutil.WriteFile(stateFile, []byte(state), 0644); err != nil {
+		return fmt.Errorf("set cgroup freezer state fail %v", err)
+	}
+	return waitFreezerState(func() (bool, error) {
+		current, err := ioutil.ReadFile(stateFile)
+		return strings.TrimSpace(string(current)) == state, err
+	})
+}
+
+// FreezeUnified drives cgroup.freeze, v2 has the freezer built into every
+// cgroup instead of a separate controller.
+func (s *FreezerSubsystem) FreezeUnified(cgroupPath string, state string) error {
+	frozen := "0"
+	if state == Frozen {
+		frozen = "1"
+	}
+	if err := ioutil.WriteFile(path.Join(cgroupPath, "cgroup.freeze"), []byte(frozen), 0644); err != nil {
+		return fmt.Errorf("set cgroup freeze fail %v", err)
+	}
+	return waitFreezerState(func() (bool, error) {
+		value, err := readKeyedValue(path.Join(cgroupPath, "cgroup.events"), "frozen")
+		return strconv.FormatUint(value, 10) == frozen, err
+	})
+}
+
+func waitFreezerState(done func() (bool, error)) error {
+	deadline := time.Now().Add(freezeTimeout)
+	for time.Now().Before(deadline) {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("timeout waiting for cgroup freezer")
+}
@@ -6,8 +6,12 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 )
 
+// minMemoryLimit is the smallest limit docker accepts, 6MB.
+const minMemoryLimit = 6 * 1024 * 1024
+
 type MemorySubsystem struct {
 }
 
@@ -17,11 +21,27 @@ func (s *MemorySubsystem) Name() string {
 
 func (s *MemorySubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
-		if res.MemoryLimit != "" {
-			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "memory.limit_in_bytes"), []byte(res.MemoryLimit), 0644); err != nil {
-				return fmt.Errorf("set cgroup memory fail %v", err)
+		if err := setMemoryLimits(subsysCgroupPath, res); err != nil {
+			return err
+		}
+		if res.MemoryReservation != "" {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "memory.soft_limit_in_bytes"), []byte(res.MemoryReservation), 0644); err != nil {
+				return fmt.Errorf("set cgroup memory reservation fail %v", err)
+			}
+		}
+		if res.OomKillDisable != nil {
+			value := "0"
+			if *res.OomKillDisable {
+				value = "1"
+			}
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "memory.oom_control"), []byte(value), 0644); err != nil {
+				return fmt.Errorf("set cgroup memory oom control fail %v", err)
+			}
+		}
+		if res.MemorySwappiness != "" {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "memory.swappiness"), []byte(res.MemorySwappiness), 0644); err != nil {
+				return fmt.Errorf("set cgroup memory swappiness fail %v", err)
 			}
-			return nil
 		}
 	} else {
 		return err
@@ -29,6 +49,52 @@ func (s *MemorySubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	return nil
 }
 
+// setMemoryLimits writes the memory and memory+swap limits, the kernel keeps
+// memsw at least as large as the memory limit so the order depends on
+// whether the limit grows or shrinks.
+func setMemoryLimits(subsysCgroupPath string, res *ResourceConfig) error {
+	limitFile := path.Join(subsysCgroupPath, "memory.limit_in_bytes")
+	swapFile := path.Join(subsysCgroupPath, "memory.memsw.limit_in_bytes")
+	writeLimit := func() error {
+		if res.MemoryLimit == "" {
+			return nil
+		}
+		if err := ioutil.WriteFile(limitFile, []byte(res.MemoryLimit), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory fail %v", err)
+		}
+		return nil
+	}
+	writeSwap := func() error {
+		if res.MemorySwap == "" {
+			return nil
+		}
+		if _, err := os.Stat(swapFile); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail, swap accounting is not enabled")
+		}
+		if err := ioutil.WriteFile(swapFile, []byte(res.MemorySwap), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+		return nil
+	}
+
+	growing := res.MemorySwap == "-1"
+	if current, err := readUintFile(limitFile); err == nil && res.MemoryLimit != "" {
+		if limit, err := ParseSize(res.MemoryLimit); err == nil && uint64(limit) > current {
+			growing = true
+		}
+	}
+	if growing {
+		if err := writeSwap(); err != nil {
+			return err
+		}
+		return writeLimit()
+	}
+	if err := writeLimit(); err != nil {
+		return err
+	}
+	return writeSwap()
+}
+
 func (s *MemorySubsystem) Apply(cgroupPath string, pid int) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
@@ -38,7 +104,6 @@ func (s *MemorySubsystem) Apply(cgroupPath string, pid int) error {
 	} else {
 		return err
 	}
-	return nil
 }
 
 func (s *MemorySubsystem) Remove(cgroupPath string) error {
@@ -47,5 +112,177 @@ func (s *MemorySubsystem) Remove(cgroupPath string) error {
 	} else {
 		return err
 	}
+}
+
+func (s *MemorySubsystem) Controller() string {
+	return "memory"
+}
+
+func (s *MemorySubsystem) Requested(res *ResourceConfig) bool {
+	return res.MemoryLimit != "" || res.MemorySwap != "" || res.MemoryReservation != "" || res.OomKillDisable != nil
+}
+
+// SetUnified maps the v1 settings onto memory.max, memory.swap.max which
+// only counts swap, memory.low and memory.oom.group. v2 can not turn the oom
+// killer off, disabling it only stops the kernel from killing the whole
+// container at once. There is no per cgroup swappiness, it is ignored.
+func (s *MemorySubsystem) SetUnified(cgroupPath string, res *ResourceConfig) error {
+	if res.MemoryLimit != "" {
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "memory.max"), []byte(res.MemoryLimit), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory fail %v", err)
+		}
+	}
+	if res.MemorySwap != "" {
+		swap, err := unifiedSwapMax(res)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "memory.swap.max"), []byte(swap), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+	}
+	if res.MemoryReservation != "" {
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "memory.low"), []byte(res.MemoryReservation), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory reservation fail %v", err)
+		}
+	}
+	if res.OomKillDisable != nil {
+		value := "1"
+		if *res.OomKillDisable {
+			value = "0"
+		}
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "memory.oom.group"), []byte(value), 0644); err != nil {
+			return fmt.Errorf("set cgroup memory oom group fail %v", err)
+		}
+	}
 	return nil
 }
+
+// unifiedSwapMax turns the v1 memory+swap total into the swap only amount.
+func unifiedSwapMax(res *ResourceConfig) (string, error) {
+	if res.MemorySwap == "-1" {
+		return "max", nil
+	}
+	swap, err := ParseSize(res.MemorySwap)
+	if err != nil {
+		return "", fmt.Errorf("parse memory swap %s fail %v", res.MemorySwap, err)
+	}
+	limit, err := ParseSize(res.MemoryLimit)
+	if err != nil {
+		return "", fmt.Errorf("memory swap requires a memory limit")
+	}
+	return strconv.FormatInt(swap-limit, 10), nil
+}
+
+// ValidateMemory checks the memory settings against each other before any
+// of them is written, sizes are expected in bytes.
+func ValidateMemory(res *ResourceConfig) error {
+	parse := func(name string, value string) (int64, error) {
+		if value == "" {
+			return 0, nil
+		}
+		if value == "-1" {
+			return -1, nil
+		}
+		// records written before sizes were converted to bytes hold units
+		number, err := ParseSize(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %s", name, value)
+		}
+		return number, nil
+	}
+	limit, err := parse("memory limit", res.MemoryLimit)
+	if err != nil {
+		return err
+	}
+	swap, err := parse("memory swap", res.MemorySwap)
+	if err != nil {
+		return err
+	}
+	reservation, err := parse("memory reservation", res.MemoryReservation)
+	if err != nil {
+		return err
+	}
+
+	if res.MemoryLimit != "" && limit < minMemoryLimit {
+		return fmt.Errorf("minimum memory limit allowed is 6MB")
+	}
+	if res.MemorySwap != "" {
+		if res.MemoryLimit == "" {
+			return fmt.Errorf("you should always set the memory limit when using memory swap")
+		}
+		if swap != -1 && swap < limit {
+			return fmt.Errorf("minimum memory swap limit should be larger than memory limit")
+		}
+	}
+	if res.MemoryReservation != "" {
+		if res.MemoryLimit != "" && reservation > limit {
+			return fmt.Errorf("minimum memory limit can not be less than memory reservation limit")
+		}
+	}
+	if res.MemorySwappiness != "" {
+		swappiness, err := strconv.ParseInt(res.MemorySwappiness, 10, 64)
+		if err != nil || swappiness < 0 || swappiness > 100 {
+			return fmt.Errorf("invalid memory swappiness %s, must be between 0 and 100", res.MemorySwappiness)
+		}
+	}
+	return nil
+}
+
+// ReadOOMKillCount returns the oom_kill counter of memory.oom_control.
+func ReadOOMKillCount(cgroupPath string) (uint64, error) {
+	subsysCgroupPath, err := GetCgroupPath("memory", cgroupPath, false)
+	if err != nil {
+		return 0, err
+	}
+	return readKeyedValue(path.Join(subsysCgroupPath, "memory.oom_control"), "oom_kill")
+}
+
+// ReadUnifiedOOMKillCount returns the oom_kill counter of memory.events.
+func ReadUnifiedOOMKillCount(cgroupPath string) (uint64, error) {
+	return readKeyedValue(path.Join(cgroupPath, "memory.events"), "oom_kill")
+}
+
+// ReadMemoryStats returns the usage without the reclaimable inactive file
+// cache, and the limit, of memory.usage_in_bytes and memory.limit_in_bytes.
+func ReadMemoryStats(cgroupPath string) (uint64, uint64, error) {
+	subsysCgroupPath, err := GetCgroupPath("memory", cgroupPath, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	usage, err := readUintFile(path.Join(subsysCgroupPath, "memory.usage_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err := readUintFile(path.Join(subsysCgroupPath, "memory.limit_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if inactive, err := readKeyedValue(path.Join(subsysCgroupPath, "memory.stat"), "total_inactive_file"); err == nil && inactive < usage {
+		usage -= inactive
+	}
+	return usage, limit, nil
+}
+
+// ReadUnifiedMemoryStats is ReadMemoryStats for memory.current and
+// memory.max, an unlimited cgroup reports a limit of 0.
+func ReadUnifiedMemoryStats(cgroupPath string) (uint64, uint64, error) {
+	usage, err := readUintFile(path.Join(cgroupPath, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var limit uint64
+	content, err := ioutil.ReadFile(path.Join(cgroupPath, "memory.max"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if value := strings.TrimSpace(string(content)); value != "max" {
+		if limit, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	if inactive, err := readKeyedValue(path.Join(cgroupPath, "memory.stat"), "inactive_file"); err == nil && inactive < usage {
+		usage -= inactive
+	}
+	return usage, limit, nil
+}
@@ -0,0 +1,86 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+)
+
+type PidsSubsystem struct {
+}
+
+func (s *PidsSubsystem) Name() string {
+	return "pids"
+}
+
+func (s *PidsSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		if res.PidsLimit != "" {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "pids.max"), []byte(pidsMax(res.PidsLimit)), 0644); err != nil {
+				return fmt.Errorf("set cgroup pids limit fail %v", err)
+			}
+		}
+	} else {
+		return err
+	}
+	return nil
+}
+
+func (s *PidsSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (s *PidsSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.RemoveAll(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *PidsSubsystem) Controller() string {
+	return "pids"
+}
+
+func (s *PidsSubsystem) Requested(res *ResourceConfig) bool {
+	return res.PidsLimit != ""
+}
+
+func (s *PidsSubsystem) SetUnified(cgroupPath string, res *ResourceConfig) error {
+	if res.PidsLimit != "" {
+		if err := ioutil.WriteFile(path.Join(cgroupPath, "pids.max"), []byte(pidsMax(res.PidsLimit)), 0644); err != nil {
+			return fmt.Errorf("set cgroup pids limit fail %v", err)
+		}
+	}
+	return nil
+}
+
+// pidsMax maps a limit of 0 or less to "max", like docker does.
+func pidsMax(limit string) string {
+	if value, err := strconv.ParseInt(limit, 10, 64); err == nil && value <= 0 {
+		return "max"
+	}
+	return limit
+}
+
+// ReadPidsCurrent returns pids.current, it counts threads too.
+func ReadPidsCurrent(cgroupPath string) (uint64, error) {
+	subsysCgroupPath, err := GetCgroupPath("pids", cgroupPath, false)
+	if err != nil {
+		return 0, err
+	}
+	return readUintFile(path.Join(subsysCgroupPath, "pids.current"))
+}
+
+func ReadUnifiedPidsCurrent(cgroupPath string) (uint64, error) {
+	return readUintFile(path.Join(cgroupPath, "pids.current"))
+}
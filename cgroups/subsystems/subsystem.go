@@ -1,9 +1,26 @@
 package subsystems
 
 type ResourceConfig struct {
-	MemoryLimit string
-	CpuShare    string
-	CpuSet      string
+	// MemoryLimit, MemorySwap and MemoryReservation are in bytes, MemorySwap
+	// is memory plus swap like docker and -1 for unlimited swap
+	MemoryLimit       string `json:"memoryLimit"`
+	MemorySwap        string `json:"memorySwap"`
+	MemoryReservation string `json:"memoryReservation"`
+	OomKillDisable    *bool  `json:"oomKillDisable,omitempty"`
+	MemorySwappiness  string `json:"memorySwappiness"`
+	CpuShare          string `json:"cpuShare"`
+	CpuSet            string `json:"cpuSet"`
+	// CpuPeriod and CpuQuota are in microseconds, a quota of -1 is unlimited
+	CpuPeriod string `json:"cpuPeriod"`
+	CpuQuota  string `json:"cpuQuota"`
+	// PidsLimit of 0 or less is unlimited
+	PidsLimit string `json:"pidsLimit"`
+	// BlkioWeight is the proportional io share in [10, 1000]
+	BlkioWeight     string           `json:"blkioWeight"`
+	DeviceReadBps   []ThrottleDevice `json:"deviceReadBps"`
+	DeviceWriteBps  []ThrottleDevice `json:"deviceWriteBps"`
+	DeviceReadIOps  []ThrottleDevice `json:"deviceReadIOps"`
+	DeviceWriteIOps []ThrottleDevice `json:"deviceWriteIOps"`
 }
 
 type Subsystem interface {
@@ -18,5 +35,19 @@ var (
 		&MemorySubsystem{},
 		&CpuSubsystem{},
 		&CpusetSubsystem{},
+		Freezer,
+		&CpuacctSubsystem{},
+		&BlkioSubsystem{},
+		&PidsSubsystem{},
 	}
+	Freezer = &FreezerSubsystem{}
 )
+
+// UnifiedSubsystem is implemented by subsystems that can also be configured
+// on the cgroup v2 unified hierarchy, cgroupPath is the absolute directory.
+// Requested reports whether res sets anything the controller has to enforce.
+type UnifiedSubsystem interface {
+	Controller() string
+	Requested(res *ResourceConfig) bool
+	SetUnified(cgroupPath string, res *ResourceConfig) error
+}
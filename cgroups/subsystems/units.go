@@ -0,0 +1,33 @@
+package subsystems
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kmgtp]?)(b|ib)?$`)
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+	"p": 1 << 50,
+}
+
+// ParseSize turns a human readable size like 512m, 2g or 1.5GiB into bytes,
+// the units are powers of 1024 as in docker.
+func ParseSize(size string) (int64, error) {
+	matches := sizePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(size)))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(value * float64(sizeUnits[matches[2]])), nil
+}
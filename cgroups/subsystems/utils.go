@@ -3,9 +3,12 @@ package subsystems
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"syscall"
 )
 
 func FindCgroupMountPoint(subsystem string) string {
@@ -33,9 +36,12 @@ func FindCgroupMountPoint(subsystem string) string {
 
 func GetCgroupPath(subsystem string, cgroupPath string, autoCreate bool) (string, error) {
 	cgroupRoot := FindCgroupMountPoint(subsystem)
+	if cgroupRoot == "" {
+		return "", fmt.Errorf("cgroup subsystem %s not mounted", subsystem)
+	}
 	if _, err := os.Stat(path.Join(cgroupRoot, cgroupPath)); err == nil || (autoCreate && os.IsNotExist(err)) {
 		if os.IsNotExist(err) {
-			if os.Mkdir(path.Join(cgroupRoot, cgroupPath), 0755); err != nil {
+			if err := os.MkdirAll(path.Join(cgroupRoot, cgroupPath), 0755); err != nil {
 				return "", fmt.Errorf("error create cgroup %v", err)
 			}
 		}
@@ -43,3 +49,103 @@ func GetCgroupPath(subsystem string, cgroupPath string, autoCreate bool) (string
 	}
 	return "", fmt.Errorf("cgroup path error")
 }
+
+const (
+	UnifiedMountPoint = "/sys/fs/cgroup"
+	cgroup2SuperMagic = 0x63677270
+)
+
+// IsCgroup2UnifiedMode reports whether the host runs the cgroup v2 hierarchy only.
+func IsCgroup2UnifiedMode() bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(UnifiedMountPoint, &st); err != nil {
+		return false
+	}
+	return st.Type == cgroup2SuperMagic
+}
+
+func GetUnifiedCgroupPath(cgroupPath string, autoCreate bool) (string, error) {
+	fullPath := path.Join(UnifiedMountPoint, cgroupPath)
+	if _, err := os.Stat(fullPath); err == nil || (autoCreate && os.IsNotExist(err)) {
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(fullPath, 0755); err != nil {
+				return "", fmt.Errorf("error create cgroup %v", err)
+			}
+		}
+		return fullPath, nil
+	}
+	return "", fmt.Errorf("cgroup path error")
+}
+
+// EnableUnifiedController turns the controller on in cgroup.subtree_control of
+// every ancestor of cgroupPath, v2 only exposes controller files to a child
+// once its parent delegates them.
+func EnableUnifiedController(cgroupPath string, controller string) error {
+	current := UnifiedMountPoint
+	for _, dir := range strings.Split(path.Dir(path.Clean("/"+cgroupPath)), "/") {
+		if dir != "" {
+			current = path.Join(current, dir)
+		}
+		content, err := ioutil.ReadFile(path.Join(current, "cgroup.subtree_control"))
+		if err != nil {
+			return err
+		}
+		if hasController(string(content), controller) {
+			continue
+		}
+		if err := ioutil.WriteFile(path.Join(current, "cgroup.subtree_control"), []byte("+"+controller), 0644); err != nil {
+			return fmt.Errorf("enable controller %s in %s fail %v", controller, current, err)
+		}
+	}
+	return nil
+}
+
+func hasController(list string, controller string) bool {
+	for _, item := range strings.Fields(list) {
+		if item == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// readKeyedValue reads one "key value" line of flat keyed cgroup files such
+// as memory.events or memory.stat.
+func readKeyedValue(file string, key string) (uint64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("key %s not found in %s", key, file)
+}
+
+func readUintFile(file string) (uint64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// ReadCgroupProcs returns the pids listed in cgroup.procs of cgroupPath.
+func ReadCgroupProcs(cgroupPath string) ([]int, error) {
+	content, err := ioutil.ReadFile(path.Join(cgroupPath, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, line := range strings.Fields(string(content)) {
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pid %s in %s", line, cgroupPath)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
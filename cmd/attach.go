@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"minidocker/container"
+	"minidocker/terminal"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const defaultDetachKeys = "ctrl-p,ctrl-q"
+
+var attachCommand = &cobra.Command{
+	Use:     "attach",
+	Short:   "attach to a detached container",
+	Long:    "attach the local stdin, stdout and stderr to a detached container",
+	Example: "minidocker attach --detach-keys ctrl-p,ctrl-q [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		detachKeys, err := cmd.Flags().GetString("detach-keys")
+		if err != nil {
+			return err
+		}
+		return AttachContainer(args[0], detachKeys)
+	},
+}
+
+func init() {
+	attachCommand.Flags().StringP("detach-keys", "", defaultDetachKeys, "key sequence for detaching from the container")
+}
+
+func AttachContainer(containerName string, detachKeys string) error {
+	keys, err := parseDetachKeys(detachKeys)
+	if err != nil {
+		return err
+	}
+	containerInfo, err := container.ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+	if containerInfo.Status != container.RUNNING {
+		return fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
+
+	socketPath := fmt.Sprintf(container.DefaultInfoLocation, containerInfo.Name) + container.AttachSocket
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("container %s is not attachable %s", containerInfo.Name, err)
+	}
+	defer conn.Close()
+
+	tty := containerInfo.Config != nil && containerInfo.Config.Tty && terminal.IsTerminal(os.Stdin.Fd())
+	if tty {
+		state, err := terminal.MakeRaw(os.Stdin.Fd())
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(os.Stdin.Fd(), state)
+
+		stopResize := forwardResize(conn)
+		defer stopResize()
+	}
+
+	detached := make(chan struct{})
+	go func() {
+		if copyInput(conn, os.Stdin, keys) {
+			close(detached)
+		}
+	}()
+	outputDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(os.Stdout, conn)
+		close(outputDone)
+	}()
+
+	select {
+	case <-detached:
+		fmt.Fprintf(os.Stderr, "\r\nread escape sequence\r\n")
+	case <-outputDone:
+	}
+	return nil
+}
+
+// forwardResize sends the host window size now and on every SIGWINCH.
+func forwardResize(conn net.Conn) func() {
+	send := func() {
+		if ws, err := terminal.GetWinsize(os.Stdin.Fd()); err == nil {
+			_ = container.WriteFrame(conn, container.FrameResize, container.EncodeWinsize(ws))
+		}
+	}
+	send()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-winch:
+				send()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(winch)
+		close(done)
+	}
+}
+
+// copyInput forwards stdin until it ends, it reports true when the detach
+// key sequence was typed. A partially typed sequence is sent on as soon as
+// it stops matching.
+func copyInput(conn net.Conn, input io.Reader, keys []byte) bool {
+	buf := make([]byte, 1024)
+	matched := 0
+	for {
+		n, err := input.Read(buf)
+		var out []byte
+		for _, b := range buf[:n] {
+			if b == keys[matched] {
+				matched++
+				if matched == len(keys) {
+					if len(out) > 0 {
+						_ = container.WriteFrame(conn, container.FrameStdin, out)
+					}
+					return true
+				}
+				continue
+			}
+			out = append(out, keys[:matched]...)
+			matched = 0
+			if b == keys[0] {
+				matched = 1
+				continue
+			}
+			out = append(out, b)
+		}
+		if len(out) > 0 {
+			if werr := container.WriteFrame(conn, container.FrameStdin, out); werr != nil {
+				return false
+			}
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// parseDetachKeys turns "ctrl-p,ctrl-q" into the bytes the terminal sends.
+func parseDetachKeys(detachKeys string) ([]byte, error) {
+	var keys []byte
+	for _, key := range strings.Split(detachKeys, ",") {
+		switch {
+		case len(key) == 1:
+			keys = append(keys, key[0])
+		case strings.HasPrefix(key, "ctrl-") && len(key) == 6:
+			c := key[5]
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if c < '@' || c > '_' {
+				return nil, fmt.Errorf("invalid detach key %s", key)
+			}
+			keys = append(keys, c-'@')
+		default:
+			return nil, fmt.Errorf("invalid detach key %s", key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty detach key sequence")
+	}
+	return keys, nil
+}
@@ -22,7 +22,11 @@ var commitCommand = &cobra.Command{
 }
 
 func Commit(containerName string, imageName string) error {
-	mntURL := fmt.Sprintf(container.MntURL, containerName) + "/"
+	containerInfo, err := container.ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+	mntURL := fmt.Sprintf(container.MntURL, containerInfo.Name) + "/"
 	imageTar := container.RootURL + "/" + imageName + ".tar"
 	if _, err := exec.Command("tar", "-czf", imageTar, "-C", mntURL, ".").CombinedOutput(); err != nil {
 		return err
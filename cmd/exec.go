@@ -5,7 +5,6 @@ import (
 	"github.com/spf13/cobra"
 	"minidocker/container"
 	"os"
-	"strings"
 
 	_ "minidocker/nsenter"
 )
@@ -14,27 +13,76 @@ var execCommand = &cobra.Command{
 	Use:     "exec",
 	Short:   "exec a command",
 	Long:    "exec a command in container",
-	Example: "minidocker exec [CONTAINER] [command]",
+	Example: "minidocker exec -it [CONTAINER] [command]",
 	Args:    cobra.MinimumNArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if os.Getenv(container.ENV_EXEC_PID) != "" {
-			logger.Infof("subProcess env %s, %s", os.Getenv(container.ENV_EXEC_PID), os.Getenv(container.ENV_EXEC_CMD))
-			return nil
+			code, err := container.RunInNamespace()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(code)
 		}
 		if len(args) < 2 {
 
 			return fmt.Errorf("requires at least 2 args")
 		}
 
-		return ExecContainer(args[0], args[1:])
+		interactive, err := cmd.Flags().GetBool("interactive")
+		if err != nil {
+			return err
+		}
+		tty, err := cmd.Flags().GetBool("tty")
+		if err != nil {
+			return err
+		}
+		env, err := cmd.Flags().GetStringSlice("env")
+		if err != nil {
+			return err
+		}
+		workDir, err := cmd.Flags().GetString("workdir")
+		if err != nil {
+			return err
+		}
+		user, err := cmd.Flags().GetString("user")
+		if err != nil {
+			return err
+		}
+		privileged, err := cmd.Flags().GetBool("privileged")
+		if err != nil {
+			return err
+		}
+		options := &container.ExecOptions{
+			Interactive: interactive,
+			Tty:         tty,
+			Env:         env,
+			WorkDir:     workDir,
+			User:        user,
+			Privileged:  privileged,
+		}
+		code, err := ExecContainer(args[0], args[1:], options)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return &StatusError{StatusCode: code}
+		}
+		return nil
 	},
 }
 
 func init() {
+	execCommand.Flags().BoolP("interactive", "i", false, "keep stdin open")
+	execCommand.Flags().BoolP("tty", "t", false, "allocate a pseudo-tty")
+	execCommand.Flags().StringSliceP("env", "e", []string{}, "set environment")
+	execCommand.Flags().StringP("workdir", "w", "", "working directory inside the container")
+	execCommand.Flags().StringP("user", "u", "", "user[:group] to run the command as")
+	execCommand.Flags().BoolP("privileged", "", false, "keep all capabilities instead of those of the container")
 	execCommand.Flags().SetInterspersed(false)
 }
 
-func ExecContainer(containerName string, commands []string) error {
-	command := strings.Join(commands, " ")
-	return container.ExecContainer(containerName, command)
+func ExecContainer(containerName string, commands []string, options *container.ExecOptions) (int, error) {
+	return container.ExecContainer(containerName, commands, options)
 }
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"minidocker/cgroups"
+	"minidocker/container"
+	"minidocker/network"
+	"os"
+	"text/template"
+)
+
+var inspectCommand = &cobra.Command{
+	Use:     "inspect",
+	Short:   "show container or network details",
+	Long:    "print the full state of containers or networks as json",
+	Example: "minidocker inspect --format '{{.Status}}' [CONTAINER|NETWORK]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		objectType, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return err
+		}
+		return Inspect(args, objectType, format)
+	},
+}
+
+func init() {
+	inspectCommand.Flags().StringP("format", "f", "", "format the output using a go template")
+	inspectCommand.Flags().StringP("type", "", "", "only inspect objects of the given type, container or network")
+}
+
+type containerInspect struct {
+	*container.Info
+	CgroupPaths map[string]string `json:"cgroupPaths"`
+	Network     *endpointInspect  `json:"network"`
+}
+
+type networkInspect struct {
+	Name      string             `json:"name"`
+	Driver    string             `json:"driver"`
+	Subnet    string             `json:"subnet"`
+	Gateway   string             `json:"gateway"`
+	Endpoints []*endpointInspect `json:"endpoints"`
+}
+
+type endpointInspect struct {
+	ID          string   `json:"id"`
+	Network     string   `json:"network"`
+	Device      string   `json:"device"`
+	PeerDevice  string   `json:"peerDevice"`
+	IPAddress   string   `json:"ipAddress"`
+	Gateway     string   `json:"gateway"`
+	MacAddress  string   `json:"macAddress"`
+	PortMapping []string `json:"portMapping"`
+}
+
+func Inspect(names []string, objectType string, format string) error {
+	if objectType != "" && objectType != "container" && objectType != "network" {
+		return fmt.Errorf("unknown type %s", objectType)
+	}
+	_ = network.Init()
+
+	var objects []interface{}
+	for _, name := range names {
+		object, err := inspectObject(name, objectType)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, object)
+	}
+
+	if format == "" {
+		content, err := json.MarshalIndent(objects, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+
+	tmpl, err := template.New("inspect").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			content, err := json.Marshal(v)
+			return string(content), err
+		},
+	}).Parse(format)
+	if err != nil {
+		return fmt.Errorf("parse format error %s", err)
+	}
+	for _, object := range objects {
+		if err = tmpl.Execute(os.Stdout, object); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func inspectObject(name string, objectType string) (interface{}, error) {
+	var containerErr error
+	if objectType != "network" {
+		containerInfo, err := container.ResolveContainer(name)
+		if err == nil {
+			return inspectContainer(containerInfo), nil
+		}
+		containerErr = err
+	}
+	if objectType != "container" {
+		n, err := network.GetNetwork(name)
+		if err == nil {
+			return inspectNetwork(n)
+		}
+		if containerErr == nil {
+			return nil, err
+		}
+	}
+	return nil, containerErr
+}
+
+func inspectContainer(containerInfo *container.Info) *containerInspect {
+	result := &containerInspect{Info: containerInfo}
+	if containerInfo.CgroupPath != "" {
+		result.CgroupPaths = cgroups.NewCgroupManager(containerInfo.CgroupPath).GetPaths()
+	}
+	if containerInfo.Config != nil && containerInfo.Config.Net != "" {
+		if ep, err := network.GetEndpoint(containerInfo.Id, containerInfo.Config.Net); err == nil {
+			result.Network = inspectEndpoint(ep)
+		}
+	}
+	return result
+}
+
+func inspectNetwork(n *network.Network) (*networkInspect, error) {
+	result := &networkInspect{
+		Name:   n.Name,
+		Driver: n.Driver,
+	}
+	if n.IPRange != nil {
+		result.Subnet = n.IPRange.String()
+		result.Gateway = n.IPRange.IP.String()
+	}
+
+	endpoints, err := network.ListEndpoints(n.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints {
+		result.Endpoints = append(result.Endpoints, inspectEndpoint(ep))
+	}
+	return result, nil
+}
+
+func inspectEndpoint(ep *network.Endpoint) *endpointInspect {
+	result := &endpointInspect{
+		ID:          ep.ID,
+		Device:      ep.Device.Name,
+		PeerDevice:  ep.Device.PeerName,
+		IPAddress:   ep.IPAddress.String(),
+		MacAddress:  ep.MacAddress.String(),
+		PortMapping: ep.PortMapping,
+	}
+	if ep.Network != nil {
+		result.Network = ep.Network.Name
+		if ep.Network.IPRange != nil {
+			result.Gateway = ep.Network.IPRange.IP.String()
+		}
+	}
+	return result
+}
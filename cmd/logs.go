@@ -22,7 +22,11 @@ func init() {
 }
 
 func LogContainer(containerName string) error {
-	if content, err := container.GetContainerLog(containerName); err != nil {
+	containerInfo, err := container.ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+	if content, err := container.GetContainerLog(containerInfo.Name); err != nil {
 		return err
 	} else {
 		fmt.Println(string(content))
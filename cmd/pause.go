@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"minidocker/container"
+)
+
+var pauseCommand = &cobra.Command{
+	Use:     "pause",
+	Short:   "pause a container",
+	Long:    "suspend all processes of a container with the cgroup freezer",
+	Example: "minidocker pause [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return container.PauseContainer(args[0])
+	},
+}
+
+var unpauseCommand = &cobra.Command{
+	Use:     "unpause",
+	Short:   "unpause a container",
+	Long:    "resume all processes of a paused container",
+	Example: "minidocker unpause [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return container.UnpauseContainer(args[0])
+	},
+}
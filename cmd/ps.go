@@ -30,9 +30,9 @@ func ListContainers() error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
-	_, _ = fmt.Fprint(w, "ID\tNAME\tPID\tSTATUS\tCOMMAND\tCREATE\n")
+	_, _ = fmt.Fprint(w, "ID\tNAME\tPID\tSTATUS\tRESTARTS\tCOMMAND\tCREATE\n")
 	for _, item := range containers {
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Id, item.Name, item.Pid, item.Status, item.Command, item.CreateTime)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", item.Id, item.Name, item.Pid, item.Status, item.RestartCount, item.Command, item.CreateTime)
 	}
 	if err = w.Flush(); err != nil {
 		return err
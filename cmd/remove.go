@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"minidocker/container"
+	"minidocker/network"
 )
 
 var removeCommand = &cobra.Command{
@@ -22,14 +23,20 @@ func init() {
 }
 
 func RemoveContainer(containerName string) error {
-	containerInfo, err := container.GetContainerInfoByName(containerName)
+	containerInfo, err := container.ResolveContainer(containerName)
 	if err != nil {
-		return fmt.Errorf("get container info by name error %s", err)
+		return err
 	}
-	if containerInfo.Status != container.STOP {
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
 		return fmt.Errorf("container is running")
 	}
 
-	container.DestroyContainer(containerInfo.Name, containerInfo.Volume)
+	if containerInfo.Config != nil && containerInfo.Config.Net != "" {
+		_ = network.Init()
+		if err = network.Disconnect(containerInfo.Config.Net, containerInfo); err != nil {
+			logger.Warnf("disconnect network error %s", err)
+		}
+	}
+	container.DestroyContainer(containerInfo)
 	return nil
 }
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"minidocker/cgroups/subsystems"
+	"strconv"
+)
+
+// addCpuQuotaFlags registers the cpu quota flags shared by run and update.
+func addCpuQuotaFlags(flags *pflag.FlagSet) {
+	flags.Float64P("cpus", "", 0, "number of cpus, like 1.5")
+	flags.Int64P("cpu-period", "", 0, "cpu cfs period in microseconds")
+	flags.Int64P("cpu-quota", "", 0, "cpu cfs quota in microseconds, -1 for unlimited")
+}
+
+// parseCpuQuotaFlags fills the cpu quota and period of res from the flags
+// that were given, --cpus is a shorthand for both.
+func parseCpuQuotaFlags(flags *pflag.FlagSet, res *subsystems.ResourceConfig) error {
+	if flags.Changed("cpus") {
+		if flags.Changed("cpu-period") || flags.Changed("cpu-quota") {
+			return fmt.Errorf("conflicting options: --cpus and --cpu-period/--cpu-quota can not both be set")
+		}
+		cpus, err := flags.GetFloat64("cpus")
+		if err != nil {
+			return err
+		}
+		if res.CpuQuota, res.CpuPeriod, err = subsystems.CpusToQuota(cpus); err != nil {
+			return err
+		}
+		return subsystems.ValidateCpuQuota(res)
+	}
+	if flags.Changed("cpu-period") {
+		period, err := flags.GetInt64("cpu-period")
+		if err != nil {
+			return err
+		}
+		res.CpuPeriod = strconv.FormatInt(period, 10)
+	}
+	if flags.Changed("cpu-quota") {
+		quota, err := flags.GetInt64("cpu-quota")
+		if err != nil {
+			return err
+		}
+		res.CpuQuota = strconv.FormatInt(quota, 10)
+	}
+	return subsystems.ValidateCpuQuota(res)
+}
+
+// parsePidsLimitFlag sets the pids limit of res when --pids-limit was given.
+func parsePidsLimitFlag(flags *pflag.FlagSet, res *subsystems.ResourceConfig) error {
+	if !flags.Changed("pids-limit") {
+		return nil
+	}
+	limit, err := flags.GetInt64("pids-limit")
+	if err != nil {
+		return err
+	}
+	res.PidsLimit = strconv.FormatInt(limit, 10)
+	return nil
+}
+
+// addBlkioFlags registers the block io flags shared by run and update.
+func addBlkioFlags(flags *pflag.FlagSet) {
+	flags.StringP("blkio-weight", "", "", "relative block io weight, between 10 and 1000")
+	flags.StringSliceP("device-read-bps", "", []string{}, "limit read rate from a device, like /dev/sda:10mb")
+	flags.StringSliceP("device-write-bps", "", []string{}, "limit write rate to a device, like /dev/sda:10mb")
+	flags.StringSliceP("device-read-iops", "", []string{}, "limit read operations per second from a device, like /dev/sda:1000")
+	flags.StringSliceP("device-write-iops", "", []string{}, "limit write operations per second to a device, like /dev/sda:1000")
+}
+
+// parseBlkioFlags fills the block io weight and device limits of res,
+// device paths are resolved to their numbers here.
+func parseBlkioFlags(flags *pflag.FlagSet, res *subsystems.ResourceConfig) error {
+	weight, err := flags.GetString("blkio-weight")
+	if err != nil {
+		return err
+	}
+	if weight != "" {
+		if err = subsystems.ValidateBlkioWeight(weight); err != nil {
+			return err
+		}
+		res.BlkioWeight = weight
+	}
+
+	throttles := []struct {
+		flag   string
+		bytes  bool
+		target *[]subsystems.ThrottleDevice
+	}{
+		{"device-read-bps", true, &res.DeviceReadBps},
+		{"device-write-bps", true, &res.DeviceWriteBps},
+		{"device-read-iops", false, &res.DeviceReadIOps},
+		{"device-write-iops", false, &res.DeviceWriteIOps},
+	}
+	for _, throttle := range throttles {
+		specs, err := flags.GetStringSlice(throttle.flag)
+		if err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			device, err := subsystems.ParseThrottleDevice(spec, throttle.bytes)
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %v", throttle.flag, err)
+			}
+			*throttle.target = append(*throttle.target, device)
+		}
+	}
+	return nil
+}
+
+// addMemoryFlags registers the memory flags other than --memory shared by run
+// and update, their defaults differ.
+func addMemoryFlags(flags *pflag.FlagSet) {
+	flags.StringP("memory-swap", "", "", "memory plus swap limit, like 2g, -1 for unlimited swap")
+	flags.StringP("memory-reservation", "", "", "memory soft limit, like 512m")
+	flags.BoolP("oom-kill-disable", "", false, "disable the oom killer")
+	flags.Int64P("memory-swappiness", "", -1, "swappiness between 0 and 100, -1 keeps the host value")
+}
+
+// parseMemoryFlags fills the memory settings of res, sizes like 512m or 2g
+// are turned into bytes.
+func parseMemoryFlags(flags *pflag.FlagSet, res *subsystems.ResourceConfig) error {
+	sizes := []struct {
+		flag   string
+		target *string
+	}{
+		{"memory", &res.MemoryLimit},
+		{"memory-swap", &res.MemorySwap},
+		{"memory-reservation", &res.MemoryReservation},
+	}
+	for _, size := range sizes {
+		value, err := flags.GetString(size.flag)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		if size.flag == "memory-swap" && value == "-1" {
+			*size.target = value
+			continue
+		}
+		bytes, err := subsystems.ParseSize(value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %v", size.flag, err)
+		}
+		*size.target = strconv.FormatInt(bytes, 10)
+	}
+
+	if flags.Changed("oom-kill-disable") {
+		disable, err := flags.GetBool("oom-kill-disable")
+		if err != nil {
+			return err
+		}
+		res.OomKillDisable = &disable
+	}
+	swappiness, err := flags.GetInt64("memory-swappiness")
+	if err != nil {
+		return err
+	}
+	if swappiness != -1 {
+		res.MemorySwappiness = strconv.FormatInt(swappiness, 10)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -23,15 +24,36 @@ var rootCommand = &cobra.Command{
 func init() {
 	rootCommand.AddCommand(runCommand)
 	rootCommand.AddCommand(initCommand)
+	rootCommand.AddCommand(shimCommand)
 	rootCommand.AddCommand(commitCommand)
 	rootCommand.AddCommand(psCommand)
 	rootCommand.AddCommand(logsCommand)
+	rootCommand.AddCommand(attachCommand)
+	rootCommand.AddCommand(inspectCommand)
 	rootCommand.AddCommand(execCommand)
+	rootCommand.AddCommand(topCommand)
+	rootCommand.AddCommand(statsCommand)
+	rootCommand.AddCommand(updateCommand)
 	rootCommand.AddCommand(stopCommand)
+	rootCommand.AddCommand(killCommand)
+	rootCommand.AddCommand(pauseCommand)
+	rootCommand.AddCommand(unpauseCommand)
+	rootCommand.AddCommand(startCommand)
+	rootCommand.AddCommand(restartCommand)
 	rootCommand.AddCommand(removeCommand)
 	rootCommand.AddCommand(networkCommand)
 }
 
+// StatusError carries the exit code of a command run inside a container,
+// the CLI exits with it instead of reporting an error.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.StatusCode)
+}
+
 func Execute() error {
 	return rootCommand.Execute()
 }
@@ -6,6 +6,8 @@ import (
 	"minidocker/cgroups/subsystems"
 	"minidocker/container"
 	"minidocker/network"
+	"minidocker/terminal"
+	"os"
 )
 
 var runCommand = &cobra.Command{
@@ -17,24 +19,46 @@ var runCommand = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tty, err := cmd.Flags().GetBool("terminal")
 		res := &subsystems.ResourceConfig{}
-		res.MemoryLimit, err = cmd.Flags().GetString("memory")
 		res.CpuShare, err = cmd.Flags().GetString("cpushare")
 		res.CpuSet, err = cmd.Flags().GetString("cpuset")
 		volume, err := cmd.Flags().GetString("volume")
 		detach, err := cmd.Flags().GetBool("detach")
-		if tty && detach {
-			return fmt.Errorf("tty and detach can not both provided")
-		} else if !(tty || detach) {
-			tty = true
-		}
 		containerName, err := cmd.Flags().GetString("name")
 		env, err := cmd.Flags().GetStringSlice("env")
 		net, err := cmd.Flags().GetString("net")
 		portMapping, err := cmd.Flags().GetStringSlice("port")
+		restart, err := cmd.Flags().GetString("restart")
+		hostname, err := cmd.Flags().GetString("hostname")
+		workdir, err := cmd.Flags().GetString("workdir")
+		user, err := cmd.Flags().GetString("user")
+		initProcess, err := cmd.Flags().GetBool("init")
+		interactive, err := cmd.Flags().GetBool("interactive")
 		imageName := args[0]
 		if err != nil {
 			return err
 		}
+		if err = parseMemoryFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = subsystems.ValidateMemory(res); err != nil {
+			return err
+		}
+		if err = parseCpuQuotaFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = parsePidsLimitFlag(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = parseBlkioFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		restartPolicy, err := container.ParseRestartPolicy(restart)
+		if err != nil {
+			return err
+		}
+		if !detach && restartPolicy.Name != container.RestartNo {
+			return fmt.Errorf("restart policy requires a detached container")
+		}
 		config := &container.Config{
 			Resource:      res,
 			Volume:        volume,
@@ -44,8 +68,15 @@ var runCommand = &cobra.Command{
 			Env:           env,
 			PortMapping:   portMapping,
 			Commands:      args[1:],
+			Hostname:      hostname,
+			WorkingDir:    workdir,
+			User:          user,
+			Init:          initProcess,
+			RestartPolicy: restartPolicy,
+			Tty:           tty,
+			Interactive:   interactive,
 		}
-		return Run(tty, config)
+		return Run(detach, config)
 	},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveNoFileComp
@@ -55,21 +86,44 @@ var runCommand = &cobra.Command{
 func init() {
 	runCommand.Flags().BoolP("terminal", "t", false, "enable tty")
 	runCommand.Flags().BoolP("detach", "d", false, "detach container")
+	runCommand.Flags().BoolP("interactive", "i", false, "keep stdin open for attach when detached")
 	runCommand.Flags().StringP("memory", "m", "1024m", "memory limit")
 	runCommand.Flags().StringP("cpushare", "", "1024", "cpushare limit")
 	runCommand.Flags().StringP("cpuset", "", "", "cpuset limit")
+	addMemoryFlags(runCommand.Flags())
+	addCpuQuotaFlags(runCommand.Flags())
+	runCommand.Flags().Int64P("pids-limit", "", 0, "maximum number of processes, 0 or -1 for unlimited")
+	addBlkioFlags(runCommand.Flags())
 	runCommand.Flags().StringP("volume", "v", "", "volume")
 	runCommand.Flags().StringP("name", "n", "", "container name")
 	runCommand.Flags().StringSliceP("env", "e", []string{}, "set environment")
 	runCommand.Flags().StringP("net", "", "", "join network")
 	runCommand.Flags().StringSliceP("port", "p", []string{}, "port mapping")
+	runCommand.Flags().StringP("hostname", "", "", "container hostname, defaults to the container id")
+	runCommand.Flags().StringP("workdir", "w", "", "working directory inside the container")
+	runCommand.Flags().StringP("user", "u", "", "user[:group] to run the command as")
+	runCommand.Flags().BoolP("init", "", false, "run an init inside the container that forwards signals and reaps processes")
+	runCommand.Flags().StringP("restart", "", container.RestartNo, "restart policy no|on-failure[:N]|always|unless-stopped")
 	runCommand.Flags().SetInterspersed(false)
 }
 
-func Run(tty bool, config *container.Config) error {
-	logger.Infof("use args : %v, %+v", tty, config)
+func Run(detach bool, config *container.Config) error {
+	logger.Infof("use args : %v, %+v", detach, config)
+
+	if detach {
+		id, err := startShim(&shimRequest{Config: config})
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+		return nil
+	}
 
-	cmd, info, err := container.NewContainer(tty, config)
+	stdio := &container.Stdio{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	if config.Tty && terminal.IsTerminal(os.Stdin.Fd()) {
+		stdio = &container.Stdio{Terminal: true}
+	}
+	cmd, info, err := container.NewContainer(config, stdio)
 	if err != nil {
 		return err
 	}
@@ -81,9 +135,16 @@ func Run(tty bool, config *container.Config) error {
 		}
 	}
 
-	if tty {
+	if stdio.Console != nil {
+		done := terminal.Proxy(stdio.Console, true)
 		_ = cmd.Wait()
-		container.DestroyContainer(config.ContainerName, config.Volume)
+		done()
+	} else {
+		_ = cmd.Wait()
+	}
+	if config.Net != "" {
+		_ = network.Disconnect(config.Net, &info)
 	}
+	container.DestroyContainer(&info)
 	return nil
 }
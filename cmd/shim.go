@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"minidocker/container"
+	"minidocker/network"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+var shimCommand = &cobra.Command{
+	Use:    "shim",
+	Short:  "supervise a detached container",
+	Long:   "start a detached container and wait for it to record its exit status",
+	Args:   cobra.MinimumNArgs(0),
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return Shim()
+	},
+}
+
+// shimRequest tells the shim either to create a container from Config or to
+// start the existing Container again.
+type shimRequest struct {
+	Config    *container.Config `json:"config"`
+	Container string            `json:"container"`
+}
+
+// shimStatus is reported back to the CLI once the container is started.
+type shimStatus struct {
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// startShim launches the supervisor in its own session so that it outlives
+// the CLI, and returns the id of the container it started.
+func startShim(request *shimRequest) (string, error) {
+	configRead, configWrite, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	statusRead, statusWrite, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("/proc/self/exe", "shim")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.ExtraFiles = []*os.File{configRead, statusWrite}
+	if err = cmd.Start(); err != nil {
+		return "", err
+	}
+	_ = configRead.Close()
+	_ = statusWrite.Close()
+	defer cmd.Process.Release()
+
+	if err = json.NewEncoder(configWrite).Encode(request); err != nil {
+		return "", err
+	}
+	_ = configWrite.Close()
+
+	content, err := ioutil.ReadAll(statusRead)
+	if err != nil {
+		return "", err
+	}
+	var status shimStatus
+	if err = json.Unmarshal(content, &status); err != nil {
+		return "", fmt.Errorf("shim exited without status")
+	}
+	if status.Error != "" {
+		return "", errors.New(status.Error)
+	}
+	return status.Id, nil
+}
+
+func Shim() error {
+	configPipe := os.NewFile(uintptr(3), "config")
+	statusPipe := os.NewFile(uintptr(4), "status")
+
+	report := func(status shimStatus) {
+		_ = json.NewEncoder(statusPipe).Encode(status)
+		_ = statusPipe.Close()
+	}
+
+	request := &shimRequest{}
+	if err := json.NewDecoder(configPipe).Decode(request); err != nil {
+		report(shimStatus{Error: fmt.Sprintf("read container config error %s", err)})
+		return err
+	}
+	_ = configPipe.Close()
+
+	var containerInfo *container.Info
+	var err error
+	logFlag := os.O_TRUNC
+	if request.Container != "" {
+		containerInfo, err = container.GetContainerInfoByName(request.Container)
+		logFlag = os.O_APPEND
+	} else {
+		containerInfo, err = container.CreateContainer(request.Config)
+	}
+	if err != nil {
+		report(shimStatus{Error: err.Error()})
+		return err
+	}
+	fail := func(err error) error {
+		report(shimStatus{Error: err.Error()})
+		if request.Container == "" {
+			// give the name and the workspace of the new container back
+			container.DestroyContainer(containerInfo)
+		}
+		return err
+	}
+
+	supervisorLock, err := container.LockSupervisor(containerInfo.Name)
+	if err != nil {
+		report(shimStatus{Error: err.Error()})
+		return err
+	}
+	defer supervisorLock.Close()
+
+	hub := container.NewStdioHub()
+	if err = hub.OpenLog(containerInfo.Name, logFlag); err != nil {
+		return fail(err)
+	}
+	listener, err := hub.Listen(containerInfo.Name)
+	if err != nil {
+		return fail(err)
+	}
+	defer listener.Close()
+
+	cmd, info, err := startWithHub(hub, containerInfo, container.StartContainer)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err = connectNetwork(&info); err != nil {
+		report(shimStatus{Error: err.Error()})
+		_ = cmd.Process.Kill()
+		err = container.WaitContainer(cmd, &info)
+		hub.Exited()
+		return err
+	}
+	report(shimStatus{Id: info.Id})
+
+	return supervise(hub, cmd, &info)
+}
+
+func startWithHub(hub *container.StdioHub, containerInfo *container.Info, start func(*container.Info, *container.Stdio) (*exec.Cmd, container.Info, error)) (*exec.Cmd, container.Info, error) {
+	stdio, err := hub.Stdio(containerInfo.Config)
+	if err != nil {
+		return nil, container.Info{}, err
+	}
+	cmd, info, err := start(containerInfo, stdio)
+	if err != nil {
+		return nil, container.Info{}, err
+	}
+	hub.Started(stdio)
+	return cmd, info, nil
+}
+
+// supervise waits for the container and relaunches it according to its
+// restart policy, doubling the delay between quick successive failures.
+func supervise(hub *container.StdioHub, cmd *exec.Cmd, info *container.Info) error {
+	delay := container.MinRestartDelay
+	for {
+		started := time.Now()
+		err := container.WaitContainer(cmd, info)
+		hub.Exited()
+		if err != nil {
+			return err
+		}
+
+		containerInfo, err := container.GetContainerInfoByName(info.Name)
+		if err != nil || !container.ShouldRestart(containerInfo) {
+			return err
+		}
+		if time.Since(started) > container.RestartResetTime {
+			delay = container.MinRestartDelay
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > container.MaxRestartDelay {
+			delay = container.MaxRestartDelay
+		}
+
+		// the user may have stopped, started or removed it while we were sleeping
+		if containerInfo, err = container.GetContainerInfoByName(info.Name); err != nil || containerInfo.Status != container.EXIT || containerInfo.ManuallyStopped {
+			return err
+		}
+		newCmd, newInfo, err := startWithHub(hub, containerInfo, container.RestartContainerByPolicy)
+		if errors.Is(err, container.ErrRestartCanceled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = connectNetwork(&newInfo); err != nil {
+			logger.Errorf("connect network error %s", err)
+		}
+		cmd, info = newCmd, &newInfo
+	}
+}
+
+func connectNetwork(info *container.Info) error {
+	if info.Config.Net == "" {
+		return nil
+	}
+	_ = network.Init()
+	// a restarted container gets a new endpoint, give back the old address
+	if err := network.Disconnect(info.Config.Net, info); err != nil {
+		logger.Warnf("disconnect network error %s", err)
+	}
+	return network.Connect(info.Config.Net, info)
+}
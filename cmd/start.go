@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"minidocker/container"
+	"time"
+)
+
+var startCommand = &cobra.Command{
+	Use:     "start",
+	Short:   "start a stopped container",
+	Long:    "start a stopped container from its persisted config",
+	Example: "minidocker start [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return StartContainer(args[0])
+	},
+}
+
+var restartCommand = &cobra.Command{
+	Use:     "restart",
+	Short:   "restart a container",
+	Long:    "stop a container if it is running and start it again",
+	Example: "minidocker restart [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := cmd.Flags().GetInt("time")
+		if err != nil {
+			return err
+		}
+		return RestartContainer(args[0], time.Duration(timeout)*time.Second)
+	},
+}
+
+func init() {
+	restartCommand.Flags().IntP("time", "t", int(container.DefaultStopTimeout/time.Second), "seconds to wait before killing the container")
+}
+
+func StartContainer(containerName string) error {
+	containerInfo, err := container.ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+		return fmt.Errorf("container %s is already running", containerInfo.Name)
+	}
+
+	if _, err = startShim(&shimRequest{Container: containerInfo.Name}); err != nil {
+		return err
+	}
+	fmt.Println(containerInfo.Name)
+	return nil
+}
+
+func RestartContainer(containerName string, timeout time.Duration) error {
+	containerInfo, err := container.ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+		if err = container.StopContainer(containerInfo.Name, timeout); err != nil {
+			return err
+		}
+	}
+	return StartContainer(containerInfo.Name)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"minidocker/container"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const statsInterval = time.Second
+
+var statsCommand = &cobra.Command{
+	Use:     "stats",
+	Short:   "display a live stream of container resource usage",
+	Long:    "display cpu, memory, network, block io and pids usage of running containers, all of them when none is given",
+	Example: "minidocker stats [CONTAINER...] --no-stream --format json",
+	Args:    cobra.MinimumNArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noStream, err := cmd.Flags().GetBool("no-stream")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %s, use table or json", format)
+		}
+		return ContainerStats(args, noStream, format)
+	},
+}
+
+func init() {
+	statsCommand.Flags().BoolP("no-stream", "", false, "print the first result and exit")
+	statsCommand.Flags().StringP("format", "", "table", "output format, table or json")
+}
+
+// ContainerStats samples the containers every statsInterval, the first
+// sample only primes the cpu counters.
+func ContainerStats(names []string, noStream bool, format string) error {
+	previous := make(map[string]*container.ContainerStats)
+	if _, err := collectStats(names, previous); err != nil {
+		return err
+	}
+	for {
+		time.Sleep(statsInterval)
+		stats, err := collectStats(names, previous)
+		if err != nil {
+			return err
+		}
+		if format == "json" {
+			err = printStatsJSON(stats)
+		} else {
+			if !noStream {
+				// clear the screen and move the cursor home
+				fmt.Print("\033[2J\033[H")
+			}
+			err = printStatsTable(stats)
+		}
+		if err != nil || noStream {
+			return err
+		}
+	}
+}
+
+func collectStats(names []string, previous map[string]*container.ContainerStats) ([]*container.ContainerStats, error) {
+	var containers []*container.Info
+	if len(names) == 0 {
+		all, err := container.GetAllContainer()
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range all {
+			if info.Status == container.RUNNING || info.Status == container.PAUSED {
+				containers = append(containers, info)
+			}
+		}
+	} else {
+		for _, name := range names {
+			info, err := container.ResolveContainer(name)
+			if err != nil {
+				return nil, err
+			}
+			containers = append(containers, info)
+		}
+	}
+
+	var result []*container.ContainerStats
+	for _, info := range containers {
+		stats, err := container.GetContainerStats(info, previous[info.Id])
+		if err != nil {
+			if len(names) == 0 {
+				// the container exited while listing
+				continue
+			}
+			return nil, err
+		}
+		previous[info.Id] = stats
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+func printStatsJSON(stats []*container.ContainerStats) error {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, item := range stats {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printStatsTable(stats []*container.ContainerStats) error {
+	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+	_, _ = fmt.Fprint(w, "ID\tNAME\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O\tPIDS\n")
+	for _, item := range stats {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s / %s\t%s / %s\t%d\n",
+			item.Id, item.Name, item.CPUPercent,
+			formatBytes(item.MemoryUsage), formatBytes(item.MemoryLimit), item.MemoryPercent,
+			formatBytes(item.NetRx), formatBytes(item.NetTx),
+			formatBytes(item.BlockRead), formatBytes(item.BlockWrite),
+			item.Pids)
+	}
+	return w.Flush()
+}
+
+// formatBytes renders a size with binary units, like 1.5MiB.
+func formatBytes(size uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d%s", size, units[0])
+	}
+	return fmt.Sprintf("%.2f%s", value, units[unit])
+}
@@ -3,15 +3,44 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 	"minidocker/container"
+	"time"
 )
 
 var stopCommand = &cobra.Command{
 	Use:     "stop",
 	Short:   "stop a container",
-	Long:    "stop a container by SIGTERM",
+	Long:    "stop a container by SIGTERM, then SIGKILL after the grace period",
 	Example: "minidocker stop [CONTAINER]",
 	Args:    cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return container.StopContainer(args[0])
+		timeout, err := cmd.Flags().GetInt("time")
+		if err != nil {
+			return err
+		}
+		return container.StopContainer(args[0], time.Duration(timeout)*time.Second)
 	},
 }
+
+var killCommand = &cobra.Command{
+	Use:     "kill",
+	Short:   "kill a container",
+	Long:    "send a signal to the init process of a container",
+	Example: "minidocker kill -s SIGHUP [CONTAINER]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		signal, err := cmd.Flags().GetString("signal")
+		if err != nil {
+			return err
+		}
+		sig, err := container.ParseSignal(signal)
+		if err != nil {
+			return err
+		}
+		return container.KillContainer(args[0], sig)
+	},
+}
+
+func init() {
+	stopCommand.Flags().IntP("time", "t", int(container.DefaultStopTimeout/time.Second), "seconds to wait before killing the container")
+	killCommand.Flags().StringP("signal", "s", "SIGKILL", "signal to send to the container")
+}
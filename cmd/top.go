@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"minidocker/container"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type topColumn struct {
+	header string
+	value  func(process *container.ProcessInfo) string
+}
+
+// topColumns maps the ps style keys accepted by -o to their column.
+var topColumns = map[string]topColumn{
+	"pid":   {"PID", func(p *container.ProcessInfo) string { return strconv.Itoa(p.Pid) }},
+	"nspid": {"NSPID", func(p *container.ProcessInfo) string { return strconv.Itoa(p.NSpid) }},
+	"user":  {"USER", func(p *container.ProcessInfo) string { return p.User }},
+	"uid":   {"UID", func(p *container.ProcessInfo) string { return strconv.FormatUint(uint64(p.Uid), 10) }},
+	"time":  {"TIME", func(p *container.ProcessInfo) string { return formatCPUTime(p.CPUTime) }},
+	"rss":   {"RSS", func(p *container.ProcessInfo) string { return strconv.FormatUint(p.RSS, 10) }},
+	"cmd":   {"CMD", func(p *container.ProcessInfo) string { return p.Command }},
+}
+
+var topColumnAliases = map[string]string{
+	"args":    "cmd",
+	"command": "cmd",
+	"cputime": "time",
+	"rssize":  "rss",
+}
+
+const defaultTopFormat = "pid,nspid,user,time,rss,cmd"
+
+var topCommand = &cobra.Command{
+	Use:     "top",
+	Short:   "display the running processes of a container",
+	Long:    "list the processes in the cgroup of a container, -o selects the columns like ps",
+	Example: "minidocker top [CONTAINER] -o pid,user,cmd",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		return ContainerTop(args[0], format)
+	},
+}
+
+func init() {
+	topCommand.Flags().StringP("format", "o", defaultTopFormat, "comma separated columns: pid, nspid, user, uid, time, rss, cmd")
+}
+
+func ContainerTop(containerName string, format string) error {
+	columns, err := parseTopColumns(format)
+	if err != nil {
+		return err
+	}
+	processes, err := container.ListContainerProcesses(containerName)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 8, 1, 3, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.header
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, process := range processes {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = column.value(process)
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+func parseTopColumns(format string) ([]topColumn, error) {
+	var columns []topColumn
+	for _, key := range strings.Split(format, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if alias, ok := topColumnAliases[key]; ok {
+			key = alias
+		}
+		column, ok := topColumns[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", key)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// formatCPUTime renders seconds the way ps prints TIME, [dd-]hh:mm:ss.
+func formatCPUTime(seconds uint64) string {
+	days := seconds / 86400
+	clock := fmt.Sprintf("%02d:%02d:%02d", seconds%86400/3600, seconds%3600/60, seconds%60)
+	if days > 0 {
+		return fmt.Sprintf("%d-%s", days, clock)
+	}
+	return clock
+}
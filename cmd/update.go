@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"minidocker/cgroups/subsystems"
+	"minidocker/container"
+)
+
+var updateCommand = &cobra.Command{
+	Use:     "update",
+	Short:   "update resource limits of containers",
+	Long:    "change the cgroup limits of running containers and record them for later starts",
+	Example: "minidocker update -m 512m --cpus 1.5 [CONTAINER...]",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res := &subsystems.ResourceConfig{}
+		var err error
+		if res.CpuShare, err = cmd.Flags().GetString("cpushare"); err != nil {
+			return err
+		}
+		if res.CpuSet, err = cmd.Flags().GetString("cpuset"); err != nil {
+			return err
+		}
+		if err = parseMemoryFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = parseCpuQuotaFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = parsePidsLimitFlag(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if err = parseBlkioFlags(cmd.Flags(), res); err != nil {
+			return err
+		}
+		if cmd.Flags().NFlag() == 0 {
+			return fmt.Errorf("you must provide one or more flags when using this command")
+		}
+		return UpdateContainers(args, res)
+	},
+}
+
+func init() {
+	updateCommand.Flags().StringP("memory", "m", "", "memory limit")
+	updateCommand.Flags().StringP("cpushare", "", "", "cpushare limit")
+	updateCommand.Flags().StringP("cpuset", "", "", "cpuset limit")
+	addMemoryFlags(updateCommand.Flags())
+	addCpuQuotaFlags(updateCommand.Flags())
+	updateCommand.Flags().Int64P("pids-limit", "", 0, "maximum number of processes, 0 or -1 for unlimited")
+	addBlkioFlags(updateCommand.Flags())
+}
+
+func UpdateContainers(names []string, res *subsystems.ResourceConfig) error {
+	var failed bool
+	for _, name := range names {
+		if err := container.UpdateContainer(name, res); err != nil {
+			logger.Errorf("update container %s error %s", name, err)
+			failed = true
+			continue
+		}
+		fmt.Println(name)
+	}
+	if failed {
+		return fmt.Errorf("failed to update some containers")
+	}
+	return nil
+}
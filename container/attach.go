@@ -0,0 +1,245 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"minidocker/terminal"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	AttachSocket string = "attach.sock"
+
+	FrameStdin  byte = 0
+	FrameResize byte = 1
+
+	// clientBuffer is the number of pending writes an attach client may fall
+	// behind by before it is dropped, a stuck client must not stall output.
+	clientBuffer       = 256
+	clientWriteTimeout = 5 * time.Second
+)
+
+// WriteFrame sends one message from an attach client to the shim, a frame is
+// a type byte followed by a big endian length and the payload.
+func WriteFrame(w io.Writer, frameType byte, payload []byte) error {
+	header := make([]byte, 5)
+	header[0] = frameType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ReadFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func EncodeWinsize(ws *terminal.Winsize) []byte {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload, ws.Rows)
+	binary.BigEndian.PutUint16(payload[2:], ws.Cols)
+	return payload
+}
+
+// StdioHub holds the stdio of a detached container inside the shim: output
+// goes to the log file and every attached client, input from any client goes
+// to the container stdin or console.
+type StdioHub struct {
+	mu       sync.Mutex
+	log      *os.File
+	clients  map[net.Conn]chan []byte
+	input    io.WriteCloser
+	console  *os.File
+	copyDone chan struct{}
+}
+
+func NewStdioHub() *StdioHub {
+	return &StdioHub{clients: make(map[net.Conn]chan []byte)}
+}
+
+// OpenLog starts writing output to the container log, flag is os.O_TRUNC
+// for a new container and os.O_APPEND when it is started again.
+func (h *StdioHub) OpenLog(containerName string, flag int) error {
+	logFile, err := os.OpenFile(fmt.Sprintf(DefaultInfoLocation, containerName)+LogFile, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return err
+	}
+	h.log = logFile
+	return nil
+}
+
+func (h *StdioHub) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.log != nil {
+		_, _ = h.log.Write(p)
+	}
+	if len(h.clients) > 0 {
+		data := append([]byte(nil), p...)
+		for conn, pending := range h.clients {
+			select {
+			case pending <- data:
+			default:
+				h.removeClient(conn)
+				_ = conn.Close()
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// addClient starts the writer of conn, it sends the queued output until the
+// client is removed and then closes the connection.
+func (h *StdioHub) addClient(conn net.Conn) {
+	pending := make(chan []byte, clientBuffer)
+	h.mu.Lock()
+	h.clients[conn] = pending
+	h.mu.Unlock()
+	go func() {
+		defer conn.Close()
+		for data := range pending {
+			_ = conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+			if _, err := conn.Write(data); err != nil {
+				h.mu.Lock()
+				h.removeClient(conn)
+				h.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// removeClient stops queueing output for conn, the caller holds h.mu.
+func (h *StdioHub) removeClient(conn net.Conn) {
+	if pending, ok := h.clients[conn]; ok {
+		delete(h.clients, conn)
+		close(pending)
+	}
+}
+
+// Stdio returns the streams for the next launch of the container.
+func (h *StdioHub) Stdio(config *Config) (*Stdio, error) {
+	if config.Tty {
+		return &Stdio{Terminal: true}, nil
+	}
+	stdio := &Stdio{Stdout: h, Stderr: h}
+	if config.Interactive {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		stdio.Stdin = reader
+		h.mu.Lock()
+		h.input = writer
+		h.mu.Unlock()
+	}
+	return stdio, nil
+}
+
+// Started must be called once the process launched with stdio is running.
+func (h *StdioHub) Started(stdio *Stdio) {
+	if reader, ok := stdio.Stdin.(*os.File); ok {
+		// the child has its own copy now
+		_ = reader.Close()
+	}
+	if stdio.Console == nil {
+		return
+	}
+	h.mu.Lock()
+	h.input = stdio.Console
+	h.console = stdio.Console
+	h.copyDone = make(chan struct{})
+	h.mu.Unlock()
+	go func(console *os.File, done chan struct{}) {
+		_, _ = io.Copy(h, console)
+		close(done)
+	}(stdio.Console, h.copyDone)
+}
+
+// Exited drops the stdio of the finished process and disconnects the clients.
+func (h *StdioHub) Exited() {
+	h.mu.Lock()
+	copyDone := h.copyDone
+	h.mu.Unlock()
+	if copyDone != nil {
+		<-copyDone
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.input != nil {
+		_ = h.input.Close()
+	}
+	h.input, h.console, h.copyDone = nil, nil, nil
+	// the writers flush what is queued before disconnecting
+	for conn := range h.clients {
+		h.removeClient(conn)
+	}
+}
+
+// Listen serves attach clients on the unix socket of the container.
+func (h *StdioHub) Listen(containerName string) (net.Listener, error) {
+	socketPath := fmt.Sprintf(DefaultInfoLocation, containerName) + AttachSocket
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			h.addClient(conn)
+			go h.serve(conn)
+		}
+	}()
+	return listener, nil
+}
+
+func (h *StdioHub) serve(conn net.Conn) {
+	defer func() {
+		h.mu.Lock()
+		h.removeClient(conn)
+		h.mu.Unlock()
+	}()
+
+	for {
+		frameType, payload, err := ReadFrame(conn)
+		if err != nil {
+			return
+		}
+		h.mu.Lock()
+		input, console := h.input, h.console
+		h.mu.Unlock()
+
+		switch frameType {
+		case FrameStdin:
+			if input != nil {
+				_, _ = input.Write(payload)
+			}
+		case FrameResize:
+			if console != nil && len(payload) == 4 {
+				_ = terminal.SetWinsize(console.Fd(), &terminal.Winsize{
+					Rows: binary.BigEndian.Uint16(payload),
+					Cols: binary.BigEndian.Uint16(payload[2:]),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// defaultCapabilities is the set docker grants to unprivileged processes,
+// exec falls back to it when the bounding set of the container is unknown.
+var defaultCapabilities = map[int]bool{
+	0:  true, // CAP_CHOWN
+	1:  true, // CAP_DAC_OVERRIDE
+	3:  true, // CAP_FOWNER
+	4:  true, // CAP_FSETID
+	5:  true, // CAP_KILL
+	6:  true, // CAP_SETGID
+	7:  true, // CAP_SETUID
+	8:  true, // CAP_SETPCAP
+	10: true, // CAP_NET_BIND_SERVICE
+	13: true, // CAP_NET_RAW
+	18: true, // CAP_SYS_CHROOT
+	27: true, // CAP_MKNOD
+	29: true, // CAP_AUDIT_WRITE
+	31: true, // CAP_SETFCAP
+}
+
+func lastCapability() int {
+	content, err := ioutil.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return 40
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 40
+	}
+	return last
+}
+
+// readCapabilityBound returns the CapBnd hex mask of pid, an exec process
+// gets no more than the init process of its container.
+func readCapabilityBound(pid string) (string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%s/status", pid))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "CapBnd:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "CapBnd:")), nil
+		}
+	}
+	return "", fmt.Errorf("no CapBnd in status of pid %s", pid)
+}
+
+// dropCapabilities removes every capability outside the bound hex mask from
+// the bounding set, or outside defaultCapabilities when bound is empty. The
+// next execve then can not gain them back.
+func dropCapabilities(bound string) error {
+	keep := func(capability int) bool {
+		return defaultCapabilities[capability]
+	}
+	if bound != "" {
+		mask, err := strconv.ParseUint(bound, 16, 64)
+		if err != nil {
+			return fmt.Errorf("parse capability bound %s error %s", bound, err)
+		}
+		keep = func(capability int) bool {
+			return mask&(1<<uint(capability)) != 0
+		}
+	}
+	for capability := 0; capability <= lastCapability(); capability++ {
+		if keep(capability) {
+			continue
+		}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_CAPBSET_DROP, uintptr(capability), 0); errno != 0 && errno != syscall.EINVAL {
+			return fmt.Errorf("drop capability %d error %v", capability, errno)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"minidocker/cgroups"
+	"minidocker/terminal"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+type ExecOptions struct {
+	Interactive bool
+	Tty         bool
+	Env         []string
+	WorkDir     string
+	User        string
+	Privileged  bool
+}
+
+// ExecContainer runs commands inside a running container and returns its
+// exit code. The command goes through a re-exec of minidocker whose nsenter
+// constructor joins the container namespaces before the go runtime starts.
+func ExecContainer(containerName string, commands []string, options *ExecOptions) (int, error) {
+	containerInfo, err := ResolveContainer(containerName)
+	if err != nil {
+		return -1, err
+	}
+	if containerInfo.Status == PAUSED {
+		return -1, fmt.Errorf("container %s is paused, unpause it first", containerInfo.Name)
+	}
+	if containerInfo.Status != RUNNING {
+		return -1, fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
+	pid := containerInfo.Pid
+
+	argv, err := json.Marshal(commands)
+	if err != nil {
+		return -1, err
+	}
+	containerEnv, err := getEnvByPid(pid)
+	if err != nil {
+		return -1, fmt.Errorf("read env of container %s error %s", containerInfo.Name, err)
+	}
+	env, err := json.Marshal(mergeEnv(containerEnv, options.Env))
+	if err != nil {
+		return -1, err
+	}
+
+	cmd := exec.Command("/proc/self/exe", "exec")
+	cmd.Env = append(os.Environ(),
+		ENV_EXEC_PID+"="+pid,
+		ENV_EXEC_CMD+"="+string(argv),
+		ENV_EXEC_ENV+"="+string(env),
+		ENV_EXEC_CWD+"="+options.WorkDir,
+		ENV_EXEC_USER+"="+options.User,
+	)
+	if options.Privileged {
+		cmd.Env = append(cmd.Env, ENV_EXEC_PRIVILEGED+"=1")
+	} else {
+		bound, err := readCapabilityBound(pid)
+		if err != nil {
+			return -1, fmt.Errorf("read capabilities of container %s error %s", containerInfo.Name, err)
+		}
+		cmd.Env = append(cmd.Env, ENV_EXEC_CAPBND+"="+bound)
+	}
+	if containerInfo.CgroupPath != "" {
+		cmd.Env = append(cmd.Env, ENV_EXEC_CGROUP+"="+execCgroupPaths(containerInfo.CgroupPath))
+	}
+
+	var console, slave *os.File
+	if options.Tty && terminal.IsTerminal(os.Stdin.Fd()) {
+		if console, slave, err = terminal.OpenPty(); err != nil {
+			return -1, fmt.Errorf("allocate pty error %s", err)
+		}
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		cmd.Env = append(cmd.Env, ENV_EXEC_TTY+"=1")
+	} else {
+		if options.Interactive {
+			cmd.Stdin = os.Stdin
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	err = cmd.Start()
+	if slave != nil {
+		_ = slave.Close()
+	}
+	if err != nil {
+		if console != nil {
+			_ = console.Close()
+		}
+		return -1, err
+	}
+	if console != nil {
+		done := terminal.Proxy(console, options.Interactive)
+		_ = cmd.Wait()
+		done()
+	} else {
+		_ = cmd.Wait()
+	}
+	return exitCodeOf(cmd.ProcessState), nil
+}
+
+// RunInNamespace is the half of exec running inside the container, the
+// nsenter constructor has already joined the namespaces and forked this
+// process into the container pid namespace, so the command replaces it.
+// The returned code is only used when the command could not be started.
+func RunInNamespace() (int, error) {
+	var argv, env []string
+	if err := json.Unmarshal([]byte(os.Getenv(ENV_EXEC_CMD)), &argv); err != nil {
+		return 126, fmt.Errorf("decode exec command error %s", err)
+	}
+	if err := json.Unmarshal([]byte(os.Getenv(ENV_EXEC_ENV)), &env); err != nil {
+		return 126, fmt.Errorf("decode exec env error %s", err)
+	}
+	if len(argv) == 0 {
+		return 126, fmt.Errorf("exec command is empty")
+	}
+	tty := os.Getenv(ENV_EXEC_TTY) != ""
+	workDir := os.Getenv(ENV_EXEC_CWD)
+	user := os.Getenv(ENV_EXEC_USER)
+	privileged := os.Getenv(ENV_EXEC_PRIVILEGED) != ""
+	bound := os.Getenv(ENV_EXEC_CAPBND)
+
+	if workDir != "" {
+		if err := os.Chdir(workDir); err != nil {
+			return 126, fmt.Errorf("chdir %s error %s", workDir, err)
+		}
+	}
+
+	// resolve the command against the PATH of the container
+	os.Clearenv()
+	for _, item := range env {
+		if key, value, ok := strings.Cut(item, "="); ok {
+			_ = os.Setenv(key, value)
+		}
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return 127, err
+	}
+
+	if tty {
+		if _, err = syscall.Setsid(); err != nil {
+			return 126, fmt.Errorf("setsid error %s", err)
+		}
+		if err = terminal.SetControllingTerminal(os.Stdin.Fd()); err != nil {
+			return 126, fmt.Errorf("set controlling terminal error %s", err)
+		}
+	}
+	if !privileged {
+		if err = dropCapabilities(bound); err != nil {
+			return 126, err
+		}
+	}
+	if user != "" {
+		if err = setUser(user); err != nil {
+			return 126, err
+		}
+	}
+	if err = syscall.Exec(path, argv, env); err != nil {
+		return 126, err
+	}
+	return 0, nil
+}
+
+// execCgroupPaths lists the cgroup directories of the container, nsenter
+// moves the exec process into them before anything of the command runs.
+func execCgroupPaths(cgroupPath string) string {
+	var dirs []string
+	for _, dir := range cgroups.NewCgroupManager(cgroupPath).GetPaths() {
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return strings.Join(dirs, ":")
+}
+
+func getEnvByPid(pid string) ([]string, error) {
+	path := fmt.Sprintf("/proc/%s/environ", pid)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env []string
+	for _, item := range strings.Split(string(content), "\u0000") {
+		if item != "" {
+			env = append(env, item)
+		}
+	}
+	return env, nil
+}
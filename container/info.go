@@ -6,8 +6,11 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -15,63 +18,117 @@ const (
 	RUNNING string = "running"
 	STOP    string = "stopped"
 	EXIT    string = "exited"
+	PAUSED  string = "paused"
 
 	DefaultInfoLocation string = "/var/run/minidocker/%s/"
 	ConfigName          string = "config.json"
 	LogFile             string = "container.log"
+	SupervisorLockFile  string = "shim.lock"
 	RootURL             string = "/root"
 	MntURL              string = "/root/mnt/%s"
 	WriteLayerURL       string = "/root/writeLayer/%s"
+	CgroupPathFormat    string = "minidocker/%s"
 )
 
 type Info struct {
-	Pid         string   `json:"pid"`
-	Id          string   `json:"id"`
-	Name        string   `json:"name"`
-	Command     string   `json:"command"`
-	CreateTime  string   `json:"createTime"`
-	Status      string   `json:"status"`
-	Volume      string   `json:"volume"`
-	PortMapping []string `json:"portMapping"`
+	Pid             string   `json:"pid"`
+	PidStartTime    uint64   `json:"pidStartTime"`
+	Id              string   `json:"id"`
+	Name            string   `json:"name"`
+	Command         string   `json:"command"`
+	CreateTime      string   `json:"createTime"`
+	Status          string   `json:"status"`
+	Volume          string   `json:"volume"`
+	PortMapping     []string `json:"portMapping"`
+	CgroupPath      string   `json:"cgroupPath"`
+	ExitCode        int      `json:"exitCode"`
+	FinishTime      string   `json:"finishTime"`
+	OOMKilled       bool     `json:"oomKilled"`
+	RestartCount    int      `json:"restartCount"`
+	ManuallyStopped bool     `json:"manuallyStopped"`
+	Config          *Config  `json:"config"`
 }
 
 var SkipList = map[string]bool{
 	"network": true,
 }
 
-func recordContainerInfo(pid int, commands []string, containerName string, id string, volume string) (*Info, error) {
+var containerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
 
-	containerInfo := &Info{
-		Pid:        strconv.Itoa(pid),
-		Id:         id,
-		Name:       containerName,
-		Command:    strings.Join(commands, " "),
-		CreateTime: time.Now().Format("2006-01-02 15:04:05"),
-		Status:     RUNNING,
-		Volume:     volume,
-	}
-	jsonBytes, err := json.Marshal(containerInfo)
+func recordContainerInfo(pid int, containerInfo *Info) (*Info, error) {
+	startTime, err := GetProcessStartTime(pid)
 	if err != nil {
 		return nil, err
 	}
+	containerInfo.Pid = strconv.Itoa(pid)
+	containerInfo.PidStartTime = startTime
+	containerInfo.Status = RUNNING
+	containerInfo.ExitCode = 0
+	containerInfo.FinishTime = ""
+	containerInfo.OOMKilled = false
+	containerInfo.ManuallyStopped = false
 
 	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerInfo.Name)
 	if err := os.MkdirAll(pathUrl, 0622); err != nil {
 		return nil, err
 	}
-	fileName := pathUrl + "/" + ConfigName
-	file, err := os.Create(fileName)
+	if err := writeContainerInfo(containerInfo); err != nil {
+		return nil, err
+	}
+	return containerInfo, nil
+}
+
+func writeContainerInfo(containerInfo *Info) error {
+	jsonBytes, err := json.Marshal(containerInfo)
+	if err != nil {
+		return fmt.Errorf("marshal container info error %s", err)
+	}
+
+	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerInfo.Name)
+	tmpFile := pathUrl + ConfigName + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, jsonBytes, 0622); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, pathUrl+ConfigName)
+}
+
+// updateContainerInfo applies update to the stored info while holding a lock
+// on the state directory, the CLI and the container shim both rewrite it.
+func updateContainerInfo(containerName string, update func(info *Info)) (*Info, error) {
+	unlock, err := lockContainerInfo(containerName)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	defer unlock()
 
-	if _, err = file.Write(jsonBytes); err != nil {
+	containerInfo, err := readContainerInfo(containerName)
+	if err != nil {
+		return nil, err
+	}
+	update(containerInfo)
+	if err = writeContainerInfo(containerInfo); err != nil {
 		return nil, err
 	}
 	return containerInfo, nil
 }
 
+// lockContainerInfo takes the lock of the state directory, the returned
+// function releases it. The lock is not reentrant.
+func lockContainerInfo(containerName string) (func(), error) {
+	dir, err := os.Open(fmt.Sprintf(DefaultInfoLocation, containerName))
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(dir.Fd()), syscall.LOCK_EX); err != nil {
+		_ = dir.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(dir.Fd()), syscall.LOCK_UN)
+		_ = dir.Close()
+	}, nil
+}
+
 func deleteContainerInfo(containerId string) {
 	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerId)
 	if err := os.RemoveAll(pathUrl); err != nil {
@@ -90,6 +147,18 @@ func RandID(length int) string {
 }
 
 func GetContainerInfoByName(containerName string) (*Info, error) {
+	containerInfo, err := readContainerInfo(containerName)
+	if err != nil {
+		return nil, err
+	}
+	return reconcileContainerInfo(containerInfo), nil
+}
+
+func GetContainerInfoByFile(file os.FileInfo) (*Info, error) {
+	return GetContainerInfoByName(file.Name())
+}
+
+func readContainerInfo(containerName string) (*Info, error) {
 	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerName)
 	content, err := ioutil.ReadFile(pathUrl + ConfigName)
 	if err != nil {
@@ -102,18 +171,66 @@ func GetContainerInfoByName(containerName string) (*Info, error) {
 	return containerInfo, nil
 }
 
-func GetContainerInfoByFile(file os.FileInfo) (*Info, error) {
-	containerName := file.Name()
-	configFile := fmt.Sprintf(DefaultInfoLocation, containerName) + ConfigName
-	content, err := ioutil.ReadFile(configFile)
+// reconcileContainerInfo marks a running container exited once its recorded
+// init process is gone, or the pid now belongs to a process started later.
+func reconcileContainerInfo(containerInfo *Info) *Info {
+	if containerInfo.Status != RUNNING && containerInfo.Status != PAUSED {
+		return containerInfo
+	}
+	if isContainerProcessAlive(containerInfo) {
+		return containerInfo
+	}
+
+	recordedPid := containerInfo.Pid
+	updated, err := updateContainerInfo(containerInfo.Name, func(info *Info) {
+		if info.Pid != recordedPid || isContainerProcessAlive(info) {
+			return
+		}
+		if info.ManuallyStopped {
+			info.Status = STOP
+		} else {
+			info.Status = EXIT
+		}
+		info.Pid = ""
+		info.FinishTime = time.Now().Format("2006-01-02 15:04:05")
+	})
 	if err != nil {
-		return nil, err
+		logger.Warnf("reconcile container %s state error %s", containerInfo.Name, err)
+		return containerInfo
 	}
-	var containerInfo = new(Info)
-	if err = json.Unmarshal(content, containerInfo); err != nil {
-		return nil, err
+	return updated
+}
+
+func isContainerProcessAlive(containerInfo *Info) bool {
+	pid, err := strconv.Atoi(containerInfo.Pid)
+	if err != nil {
+		return false
 	}
-	return containerInfo, nil
+	if containerInfo.PidStartTime == 0 {
+		return isLegacyProcessAlive(pid, containerInfo.CreateTime)
+	}
+	startTime, err := GetProcessStartTime(pid)
+	if err != nil {
+		return false
+	}
+	return containerInfo.PidStartTime == startTime
+}
+
+// isLegacyProcessAlive checks records written before the start time was
+// tracked, they were created right after their init process started so a
+// process started later has reused the pid. Unverifiable records count as
+// exited rather than letting stop or kill signal an unrelated process.
+func isLegacyProcessAlive(pid int, createTime string) bool {
+	created, err := time.ParseInLocation("2006-01-02 15:04:05", createTime, time.Local)
+	if err != nil {
+		return false
+	}
+	startedAt, err := GetProcessStartedAt(pid)
+	if err != nil {
+		return false
+	}
+	// createTime is truncated to the second and btime is rounded
+	return !startedAt.After(created.Add(2 * time.Second))
 }
 
 func GetAllContainer() ([]*Info, error) {
@@ -139,6 +256,62 @@ func GetAllContainer() ([]*Info, error) {
 	return containers, nil
 }
 
+// ResolveContainer finds a container by full id, name or unique id prefix,
+// in that order of precedence.
+func ResolveContainer(ref string) (*Info, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("empty container reference")
+	}
+	containers, err := GetAllContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, containerInfo := range containers {
+		if containerInfo.Id == ref {
+			return containerInfo, nil
+		}
+	}
+	for _, containerInfo := range containers {
+		if containerInfo.Name == ref {
+			return containerInfo, nil
+		}
+	}
+
+	var matches []*Info
+	for _, containerInfo := range containers {
+		if strings.HasPrefix(containerInfo.Id, ref) {
+			matches = append(matches, containerInfo)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no such container %s", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("container id prefix %s is ambiguous, matches %d containers", ref, len(matches))
+}
+
+// reserveContainerName creates the state directory of a new container, the
+// creation itself is the uniqueness check so two runs can not share a name.
+func reserveContainerName(containerName string) error {
+	if !containerNamePattern.MatchString(containerName) || SkipList[containerName] {
+		return fmt.Errorf("invalid container name %s", containerName)
+	}
+	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerName)
+	if err := os.MkdirAll(path.Dir(path.Clean(pathUrl)), 0622); err != nil {
+		return err
+	}
+	if err := os.Mkdir(pathUrl, 0622); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("container name %s is already in use", containerName)
+		}
+		return err
+	}
+	return nil
+}
+
 func GetContainerLog(containerName string) ([]byte, error) {
 	pathUrl := fmt.Sprintf(DefaultInfoLocation, containerName)
 	file, err := os.Open(pathUrl + LogFile)
@@ -2,7 +2,6 @@ package container
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,31 +11,80 @@ import (
 
 func Init() error {
 	pipe := os.NewFile(uintptr(3), "pipe")
-	msg, err := ioutil.ReadAll(pipe)
+	msg, err := readInitMessage(pipe)
 	if err != nil {
 		return err
 	}
-	commands := strings.Split(string(msg), " ")
-	if commands == nil || len(commands) == 0 {
-		return fmt.Errorf("run container get command error, args is nil")
-	}
+	_ = pipe.Close()
 
-	if err = setupMount(); err != nil {
+	if err = setupMount(msg.Mounts); err != nil {
 		return err
 	}
+	if msg.Hostname != "" {
+		if err = syscall.Sethostname([]byte(msg.Hostname)); err != nil {
+			return fmt.Errorf("set hostname error %v", err)
+		}
+	}
+	if msg.Cwd != "" {
+		if err = os.Chdir(msg.Cwd); err != nil {
+			return fmt.Errorf("chdir %s error %v", msg.Cwd, err)
+		}
+	}
 
-	path, err := exec.LookPath(commands[0])
+	// LookPath resolves against the PATH of the container environment
+	os.Clearenv()
+	for _, env := range msg.Env {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			_ = os.Setenv(key, value)
+		}
+	}
+	path, err := exec.LookPath(msg.Args[0])
 	if err != nil {
 		return err
 	}
 
-	if err := syscall.Exec(path, commands, os.Environ()); err != nil {
+	if msg.Init {
+		var credential *syscall.Credential
+		if msg.User != "" {
+			uid, gid, err := LookupUser(msg.User)
+			if err != nil {
+				return err
+			}
+			credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}}
+		}
+		return runAsInit(path, msg, credential)
+	}
+
+	if msg.User != "" {
+		if err = setUser(msg.User); err != nil {
+			return err
+		}
+	}
+
+	if err := syscall.Exec(path, msg.Args, msg.Env); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setUser(user string) error {
+	uid, gid, err := LookupUser(user)
+	if err != nil {
 		return err
 	}
+	if err = syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("setgroups error %v", err)
+	}
+	if err = syscall.Setgid(int(gid)); err != nil {
+		return fmt.Errorf("setgid error %v", err)
+	}
+	if err = syscall.Setuid(int(uid)); err != nil {
+		return fmt.Errorf("setuid error %v", err)
+	}
 	return nil
 }
 
-func setupMount() error {
+func setupMount(mounts []Mount) error {
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -51,9 +99,14 @@ func setupMount() error {
 		mount namespace default shared
 	*/
 	//syscall.Mount("", "/", "", syscall.MS_PRIVATE|syscall.MS_REC, "")
-	defaultMountFlags := syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV
-	_ = syscall.Mount("proc", "/proc", "proc", uintptr(defaultMountFlags), "")
-	_ = syscall.Mount("tmpfs", "/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_STRICTATIME, "mode=755")
+	for _, m := range mounts {
+		if err = os.MkdirAll(m.Destination, 0755); err != nil {
+			return err
+		}
+		if err = syscall.Mount(m.Source, m.Destination, m.Type, m.Flags, m.Data); err != nil {
+			logger.Warnf("mount %s on %s error %v", m.Source, m.Destination, err)
+		}
+	}
 	return nil
 }
 
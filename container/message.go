@@ -0,0 +1,108 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// InitMessageVersion is bumped whenever InitMessage changes incompatibly, a
+// container init refuses messages it does not understand.
+const InitMessageVersion = 1
+
+// InitMessage is the process spec the CLI hands to the container init over
+// the pipe, argv is kept as a list so arguments survive untouched.
+type InitMessage struct {
+	Version  int      `json:"version"`
+	Args     []string `json:"args"`
+	Env      []string `json:"env"`
+	Cwd      string   `json:"cwd"`
+	Hostname string   `json:"hostname"`
+	Mounts   []Mount  `json:"mounts"`
+	User     string   `json:"user"`
+	Init     bool     `json:"init"`
+}
+
+// Mount is applied inside the container after pivot_root.
+type Mount struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	Type        string  `json:"type"`
+	Flags       uintptr `json:"flags"`
+	Data        string  `json:"data"`
+}
+
+var defaultMounts = []Mount{
+	{
+		Source:      "proc",
+		Destination: "/proc",
+		Type:        "proc",
+		Flags:       syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV,
+	},
+	{
+		Source:      "tmpfs",
+		Destination: "/dev",
+		Type:        "tmpfs",
+		Flags:       syscall.MS_NOSUID | syscall.MS_STRICTATIME,
+		Data:        "mode=755",
+	},
+}
+
+func newInitMessage(containerInfo *Info) *InitMessage {
+	config := containerInfo.Config
+	hostname := config.Hostname
+	if hostname == "" {
+		hostname = containerInfo.Id
+	}
+	return &InitMessage{
+		Version:  InitMessageVersion,
+		Args:     config.Commands,
+		Env:      mergeEnv(os.Environ(), config.Env),
+		Cwd:      config.WorkingDir,
+		Hostname: hostname,
+		Mounts:   defaultMounts,
+		User:     config.User,
+		Init:     config.Init,
+	}
+}
+
+// mergeEnv joins environment lists, a later KEY=value replaces an earlier
+// one like exec.Cmd does. execve keeps duplicates and getenv returns the
+// first of them, so the overrides would otherwise be ignored.
+func mergeEnv(lists ...[]string) []string {
+	var merged []string
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, item := range list {
+			key, _, _ := strings.Cut(item, "=")
+			if i, ok := index[key]; ok {
+				merged[i] = item
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+func writeInitMessage(w io.Writer, msg *InitMessage) error {
+	return json.NewEncoder(w).Encode(msg)
+}
+
+func readInitMessage(r io.Reader) (*InitMessage, error) {
+	msg := &InitMessage{}
+	if err := json.NewDecoder(r).Decode(msg); err != nil {
+		return nil, fmt.Errorf("decode init message error %s", err)
+	}
+	if msg.Version != InitMessageVersion {
+		return nil, fmt.Errorf("unsupported init message version %d, want %d", msg.Version, InitMessageVersion)
+	}
+	if len(msg.Args) == 0 {
+		return nil, fmt.Errorf("run container get command error, args is nil")
+	}
+	return msg, nil
+}
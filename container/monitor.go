@@ -0,0 +1,49 @@
+package container
+
+import (
+	"minidocker/cgroups"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// WaitContainer reaps the container init process and records its exit code,
+// finish time and whether the memory controller killed it.
+func WaitContainer(cmd *exec.Cmd, containerInfo *Info) error {
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath)
+	oomBefore, _ := cgroupManager.OOMKillCount()
+
+	_ = cmd.Wait()
+	exitCode := exitCodeOf(cmd.ProcessState)
+	oomAfter, _ := cgroupManager.OOMKillCount()
+
+	pid := strconv.Itoa(cmd.Process.Pid)
+	_, err := updateContainerInfo(containerInfo.Name, func(info *Info) {
+		if info.Pid != pid {
+			return
+		}
+		if info.ManuallyStopped {
+			info.Status = STOP
+		} else {
+			info.Status = EXIT
+		}
+		info.Pid = ""
+		info.ExitCode = exitCode
+		info.FinishTime = time.Now().Format("2006-01-02 15:04:05")
+		info.OOMKilled = oomAfter > oomBefore
+	})
+	return err
+}
+
+// exitCodeOf follows the shell convention of 128+signal for killed processes.
+func exitCodeOf(state *os.ProcessState) int {
+	if state == nil {
+		return -1
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
@@ -0,0 +1,62 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcessStat returns the fields of /proc/<pid>/stat that follow the
+// command name, the name itself may contain spaces and parentheses.
+func readProcessStat(pid int) ([]string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	stat := string(content)
+	index := strings.LastIndex(stat, ")")
+	if index < 0 {
+		return nil, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return strings.Fields(stat[index+1:]), nil
+}
+
+// GetProcessStartTime returns the starttime field of /proc/<pid>/stat, in
+// clock ticks since boot, which tells a reused pid apart from the original.
+func GetProcessStartTime(pid int) (uint64, error) {
+	fields, err := readProcessStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	// field 22 of stat, the slice starts at field 3
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// GetProcessStartedAt converts the starttime of pid to wall clock time, it is
+// only accurate to about a second since btime is.
+func GetProcessStartedAt(pid int) (time.Time, error) {
+	startTime, err := GetProcessStartTime(pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	content, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			bootTime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(bootTime, 0).Add(time.Duration(startTime) * time.Second / clockTicks), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
@@ -1,167 +1,380 @@
 package container
 
 import (
-	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
-	"io/ioutil"
+	"io"
 	"minidocker/cgroups"
 	"minidocker/cgroups/subsystems"
+	"minidocker/terminal"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 const (
-	ENV_EXEC_PID = "minidocker_pid"
-	ENV_EXEC_CMD = "minidocker_cmd"
+	ENV_EXEC_PID        = "minidocker_pid"
+	ENV_EXEC_CMD        = "minidocker_cmd"
+	ENV_EXEC_ENV        = "minidocker_env"
+	ENV_EXEC_TTY        = "minidocker_tty"
+	ENV_EXEC_CWD        = "minidocker_cwd"
+	ENV_EXEC_USER       = "minidocker_user"
+	ENV_EXEC_PRIVILEGED = "minidocker_privileged"
+	ENV_EXEC_CGROUP     = "minidocker_cgroup"
+	ENV_EXEC_CAPBND     = "minidocker_capbnd"
+
+	DefaultStopTimeout = 10 * time.Second
+	killTimeout        = 10 * time.Second
 )
 
 var logger = zap.NewExample().Sugar()
 
 type Config struct {
-	Resource      *subsystems.ResourceConfig
-	Volume        string
-	ContainerName string
-	ImageName     string
-	Net           string
-	Env           []string
-	PortMapping   []string
-	Commands      []string
+	Resource      *subsystems.ResourceConfig `json:"resource"`
+	Volume        string                     `json:"volume"`
+	ContainerName string                     `json:"containerName"`
+	ImageName     string                     `json:"imageName"`
+	Net           string                     `json:"net"`
+	Env           []string                   `json:"env"`
+	PortMapping   []string                   `json:"portMapping"`
+	Commands      []string                   `json:"commands"`
+	Hostname      string                     `json:"hostname"`
+	WorkingDir    string                     `json:"workingDir"`
+	User          string                     `json:"user"`
+	Init          bool                       `json:"init"`
+	RestartPolicy RestartPolicy              `json:"restartPolicy"`
+	Tty           bool                       `json:"tty"`
+	Interactive   bool                       `json:"interactive"`
 }
 
-func NewContainer(tty bool, config *Config) (*exec.Cmd, Info, error) {
-	readPipe, writePipe, err := os.Pipe()
+// Stdio wires the container init to its caller. With Terminal set a pty is
+// allocated, its slave becomes the controlling terminal of the container and
+// the master is handed back in Console. When no stream is given the output
+// goes to the container log file.
+type Stdio struct {
+	Terminal bool
+	Stdin    io.Reader
+	Stdout   io.Writer
+	Stderr   io.Writer
+	Console  *os.File
+}
+
+func NewContainer(config *Config, stdio *Stdio) (*exec.Cmd, Info, error) {
+	containerInfo, err := CreateContainer(config)
 	if err != nil {
 		return nil, Info{}, err
 	}
+	cmd, info, err := launchContainer(containerInfo, stdio, os.O_TRUNC)
+	if err != nil {
+		// give the name and the workspace back
+		DestroyContainer(containerInfo)
+		return nil, Info{}, err
+	}
+	return cmd, info, nil
+}
 
+// CreateContainer reserves the name and prepares the workspace of a new
+// container without starting it.
+func CreateContainer(config *Config) (*Info, error) {
 	id := RandID(10)
 	if len(config.ContainerName) == 0 {
 		config.ContainerName = id
 	}
+	if err := reserveContainerName(config.ContainerName); err != nil {
+		return nil, err
+	}
+
+	NewWorkSpace(config.Volume, config.ContainerName, config.ImageName)
+
+	return &Info{
+		Id:          id,
+		Name:        config.ContainerName,
+		Command:     strings.Join(config.Commands, " "),
+		CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+		Volume:      config.Volume,
+		PortMapping: config.PortMapping,
+		CgroupPath:  fmt.Sprintf(CgroupPathFormat, id),
+		Config:      config,
+	}, nil
+}
+
+// StartContainer launches a stopped container again from its persisted
+// config, on top of the write layer left behind by the previous run.
+func StartContainer(containerInfo *Info, stdio *Stdio) (*exec.Cmd, Info, error) {
+	if containerInfo.Config == nil {
+		return nil, Info{}, fmt.Errorf("container %s has no persisted config", containerInfo.Name)
+	}
+	if containerInfo.Status == RUNNING || containerInfo.Status == PAUSED {
+		return nil, Info{}, fmt.Errorf("container %s is already running", containerInfo.Name)
+	}
+
+	config := containerInfo.Config
+	ReuseWorkSpace(config.Volume, config.ContainerName, config.ImageName)
+	return launchContainer(containerInfo, stdio, os.O_APPEND)
+}
+
+func launchContainer(containerInfo *Info, stdio *Stdio, logFlag int) (*exec.Cmd, Info, error) {
+	config := containerInfo.Config
+	readPipe, writePipe, err := os.Pipe()
+	if err != nil {
+		return nil, Info{}, err
+	}
 
 	cmd := exec.Command("/proc/self/exe", "init")
 	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWUTS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC}
-	if tty {
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	if stdio.Terminal {
+		console, slave, err := terminal.OpenPty()
+		if err != nil {
+			return nil, Info{}, fmt.Errorf("allocate pty error %s", err)
+		}
+		defer slave.Close()
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.SysProcAttr.Ctty = 0
+		stdio.Console = console
+	} else if stdio.Stdin != nil || stdio.Stdout != nil || stdio.Stderr != nil {
+		cmd.Stdin = stdio.Stdin
+		cmd.Stdout = stdio.Stdout
+		cmd.Stderr = stdio.Stderr
 	} else {
 		pathUrl := fmt.Sprintf(DefaultInfoLocation, config.ContainerName)
 		if err := os.MkdirAll(pathUrl, 0622); err != nil {
 			return nil, Info{}, err
 		}
 		logFilePath := pathUrl + LogFile
-		logFile, err := os.Create(logFilePath)
+		logFile, err := os.OpenFile(logFilePath, os.O_WRONLY|os.O_CREATE|logFlag, 0644)
 		if err != nil {
 			return nil, Info{}, err
 		}
+		defer logFile.Close()
 		cmd.Stdout = logFile
 	}
 	cmd.ExtraFiles = []*os.File{readPipe}
 	cmd.Env = append(os.Environ(), config.Env...)
-
-	NewWorkSpace(config.Volume, config.ContainerName, config.ImageName)
 	cmd.Dir = fmt.Sprintf(MntURL, config.ContainerName)
 
 	if err = cmd.Start(); err != nil {
+		if stdio.Console != nil {
+			_ = stdio.Console.Close()
+		}
 		return nil, Info{}, err
 	}
+	_ = readPipe.Close()
 
-	info, err := recordContainerInfo(cmd.Process.Pid, config.Commands, config.ContainerName, id, config.Volume)
+	info, err := recordContainerInfo(cmd.Process.Pid, containerInfo)
 	if err != nil {
+		killLaunched(cmd, writePipe, stdio)
 		return nil, Info{}, fmt.Errorf("record container info error %s", err)
 	}
 
-	cgroupManager := cgroups.NewCgroupManager("minidocker-cgroup")
-	defer cgroupManager.Destroy()
-	_ = cgroupManager.Set(config.Resource)
-	_ = cgroupManager.Apply(cmd.Process.Pid)
+	cgroupManager := cgroups.NewCgroupManager(info.CgroupPath)
+	if err = cgroupManager.Set(config.Resource); err != nil {
+		abortLaunch(cmd, writePipe, stdio, info)
+		return nil, Info{}, fmt.Errorf("set cgroup error %s", err)
+	}
+	// a container stopped while paused leaves its cgroup frozen behind
+	if err = cgroupManager.Freeze(subsystems.Thawed); err != nil {
+		abortLaunch(cmd, writePipe, stdio, info)
+		return nil, Info{}, fmt.Errorf("thaw cgroup error %s", err)
+	}
+	if err = cgroupManager.Apply(cmd.Process.Pid); err != nil {
+		abortLaunch(cmd, writePipe, stdio, info)
+		return nil, Info{}, fmt.Errorf("apply cgroup error %s", err)
+	}
 
-	_, _ = writePipe.WriteString(strings.Join(config.Commands, " "))
+	if err = writeInitMessage(writePipe, newInitMessage(info)); err != nil {
+		logger.Errorf("write init message error %s", err)
+	}
 	_ = writePipe.Close()
 
 	return cmd, *info, nil
 }
 
-func StopContainer(containerName string) error {
-	containerInfo, err := GetContainerInfoByName(containerName)
+// killLaunched gets rid of an init process that could not be set up, it is
+// still blocked reading its init message.
+func killLaunched(cmd *exec.Cmd, writePipe *os.File, stdio *Stdio) {
+	_ = writePipe.Close()
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if stdio.Console != nil {
+		_ = stdio.Console.Close()
+		stdio.Console = nil
+	}
+}
+
+// abortLaunch kills an init process whose cgroup could not be set up and
+// records the container as stopped again. It writes the state like
+// recordContainerInfo does, without taking the state lock, since
+// RestartContainerByPolicy already holds it around the launch.
+func abortLaunch(cmd *exec.Cmd, writePipe *os.File, stdio *Stdio, info *Info) {
+	killLaunched(cmd, writePipe, stdio)
+	info.Status = STOP
+	info.Pid = ""
+	info.PidStartTime = 0
+	info.FinishTime = time.Now().Format("2006-01-02 15:04:05")
+	if err := writeContainerInfo(info); err != nil {
+		logger.Warnf("update container %s state error %s", info.Name, err)
+	}
+}
+
+// StopContainer sends SIGTERM to the init process and escalates to SIGKILL
+// once timeout has passed, the container is only marked stopped once it is gone.
+func StopContainer(containerName string, timeout time.Duration) error {
+	containerInfo, err := ResolveContainer(containerName)
 	if err != nil {
-		return fmt.Errorf("get container info by name error %s", err)
+		return err
+	}
+	if containerInfo.Status == EXIT && hasSupervisor(containerInfo.Name) {
+		// the shim is waiting out the restart delay, cancel the restart
+		stopped := false
+		if _, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+			if info.Status == EXIT {
+				info.Status = STOP
+				info.ManuallyStopped = true
+				stopped = true
+			}
+		}); err != nil {
+			return fmt.Errorf("write container config file error %s", err)
+		}
+		if stopped {
+			return nil
+		}
+		// the shim relaunched it first, stop the new process instead
+		if containerInfo, err = GetContainerInfoByName(containerInfo.Name); err != nil {
+			return err
+		}
+	}
+	if containerInfo.Status != RUNNING && containerInfo.Status != PAUSED {
+		return fmt.Errorf("container %s is not running", containerInfo.Name)
 	}
 	pid, err := strconv.Atoi(containerInfo.Pid)
 	if err != nil {
 		return fmt.Errorf("get container pid error %s", err)
 	}
 
-	if err = syscall.Kill(pid, syscall.SIGTERM); err != nil {
-		return fmt.Errorf("stop container error %s", err)
+	// tell the shim not to apply the restart policy to this exit
+	if _, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+		info.ManuallyStopped = true
+	}); err != nil {
+		return fmt.Errorf("write container config file error %s", err)
 	}
 
-	containerInfo.Status = STOP
-	containerInfo.Pid = ""
-
-	newContent, err := json.Marshal(containerInfo)
-	if err != nil {
-		return fmt.Errorf("marshal container info error %s", err)
+	if err = syscall.Kill(pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("stop container error %s", err)
+	}
+	if containerInfo.Status == PAUSED {
+		// a frozen process can not handle the signal until it is thawed
+		if err = cgroups.NewCgroupManager(containerInfo.CgroupPath).Freeze(subsystems.Thawed); err != nil {
+			logger.Warnf("thaw container %s error %s", containerInfo.Name, err)
+		}
+	}
+	if !WaitProcessExit(pid, timeout) {
+		logger.Infof("container %s did not exit within %s, sending SIGKILL", containerInfo.Name, timeout)
+		if err = syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return fmt.Errorf("kill container error %s", err)
+		}
+		if !WaitProcessExit(pid, killTimeout) {
+			return fmt.Errorf("container %s did not exit after SIGKILL", containerInfo.Name)
+		}
 	}
 
-	configFile := fmt.Sprintf(DefaultInfoLocation, containerName) + ConfigName
-	if err = ioutil.WriteFile(configFile, newContent, 0622); err != nil {
+	if _, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+		if info.Pid != containerInfo.Pid {
+			return
+		}
+		info.Status = STOP
+		info.Pid = ""
+	}); err != nil {
 		logger.Warnf("write container config file error %s", err)
 	}
 	return nil
 }
 
-func DestroyContainer(containerName, volume string) {
-	deleteContainerInfo(containerName)
-	DeleteWorkSpace(volume, containerName)
-}
-
-func ExecContainer(containerName, command string) error {
-	pid, err := getContainerPidByName(containerName)
+// KillContainer delivers sig to the init process, the state is left to
+// whoever reaps it.
+func KillContainer(containerName string, sig syscall.Signal) error {
+	containerInfo, err := ResolveContainer(containerName)
 	if err != nil {
-		return fmt.Errorf("get container pid by name error %s", err)
+		return err
+	}
+	if containerInfo.Status == PAUSED {
+		return fmt.Errorf("container %s is paused, unpause it first", containerInfo.Name)
+	}
+	if containerInfo.Status != RUNNING {
+		return fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
+	pid, err := strconv.Atoi(containerInfo.Pid)
+	if err != nil {
+		return fmt.Errorf("get container pid error %s", err)
 	}
 
-	logger.Infof("process %s run %s", pid, command)
-	cmd := exec.Command("/proc/self/exe", "exec")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if err = syscall.Kill(pid, sig); err != nil {
+		return fmt.Errorf("kill container error %s", err)
+	}
+	return nil
+}
 
-	_ = os.Setenv(ENV_EXEC_PID, pid)
-	defer os.Unsetenv(ENV_EXEC_PID)
-	_ = os.Setenv(ENV_EXEC_CMD, command)
-	defer os.Unsetenv(ENV_EXEC_CMD)
+// PauseContainer freezes every process in the container cgroup.
+func PauseContainer(containerName string) error {
+	containerInfo, err := ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+	if containerInfo.Status != RUNNING {
+		return fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
 
-	containerEnv := getEnvByPid(pid)
-	cmd.Env = append(os.Environ(), containerEnv...)
+	if err = cgroups.NewCgroupManager(containerInfo.CgroupPath).Freeze(subsystems.Frozen); err != nil {
+		return fmt.Errorf("freeze container error %s", err)
+	}
+	_, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+		info.Status = PAUSED
+	})
+	return err
+}
 
-	if err = cmd.Run(); err != nil {
+func UnpauseContainer(containerName string) error {
+	containerInfo, err := ResolveContainer(containerName)
+	if err != nil {
 		return err
 	}
-	return nil
+	if containerInfo.Status != PAUSED {
+		return fmt.Errorf("container %s is not paused", containerInfo.Name)
+	}
+
+	if err = cgroups.NewCgroupManager(containerInfo.CgroupPath).Freeze(subsystems.Thawed); err != nil {
+		return fmt.Errorf("thaw container error %s", err)
+	}
+	_, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+		info.Status = RUNNING
+	})
+	return err
 }
 
-func getContainerPidByName(containerName string) (string, error) {
-	containerInfo, err := GetContainerInfoByName(containerName)
-	if err != nil {
-		return "", err
+// WaitProcessExit polls until pid is gone, it reports false on timeout.
+func WaitProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, 0); err == syscall.ESRCH {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
 	}
-	return containerInfo.Pid, nil
+	return false
 }
 
-func getEnvByPid(pid string) []string {
-	path := fmt.Sprintf("/proc/%s/environ", pid)
-	content, err := ioutil.ReadFile(path)
-	if err != nil {
-		logger.Errorf("get process env error %s", err)
-		return nil
+func DestroyContainer(containerInfo *Info) {
+	deleteContainerInfo(containerInfo.Name)
+	DeleteWorkSpace(containerInfo.Volume, containerInfo.Name)
+	if containerInfo.CgroupPath != "" {
+		if err := cgroups.NewCgroupManager(containerInfo.CgroupPath).Destroy(); err != nil {
+			logger.Errorf("remove cgroup %s error %s", containerInfo.CgroupPath, err)
+		}
 	}
-	env := strings.Split(string(content), "\u0000")
-	return env
 }
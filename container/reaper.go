@@ -0,0 +1,61 @@
+package container
+
+import (
+	"minidocker/terminal"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// runAsInit keeps minidocker as PID 1 of the container: the command runs as
+// a child in its own process group, signals sent to PID 1 are forwarded to
+// that group, every orphan is reaped and the exit code of the child becomes
+// the exit code of the container.
+func runAsInit(path string, msg *InitMessage, credential *syscall.Credential) error {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+
+	cmd := exec.Command(path)
+	cmd.Args = msg.Args
+	cmd.Env = msg.Env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: credential}
+	if terminal.IsTerminal(os.Stdin.Fd()) {
+		// let the child read from the terminal instead of being stopped by SIGTTIN
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	childPid := cmd.Process.Pid
+
+	for sig := range signals {
+		if sig == syscall.SIGURG {
+			// used by the go runtime for goroutine preemption
+			continue
+		}
+		if sig != syscall.SIGCHLD {
+			_ = syscall.Kill(-childPid, sig.(syscall.Signal))
+			continue
+		}
+		for {
+			var status syscall.WaitStatus
+			pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if err != nil || pid <= 0 {
+				break
+			}
+			if pid != childPid {
+				continue
+			}
+			if status.Signaled() {
+				os.Exit(128 + int(status.Signal()))
+			}
+			os.Exit(status.ExitStatus())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	RestartNo            string = "no"
+	RestartAlways        string = "always"
+	RestartOnFailure     string = "on-failure"
+	RestartUnlessStopped string = "unless-stopped"
+
+	MinRestartDelay time.Duration = 100 * time.Millisecond
+	MaxRestartDelay time.Duration = time.Minute
+	// RestartResetTime is how long a container has to stay up before the
+	// backoff delay starts over from MinRestartDelay.
+	RestartResetTime time.Duration = 10 * time.Second
+)
+
+type RestartPolicy struct {
+	Name              string `json:"name"`
+	MaximumRetryCount int    `json:"maximumRetryCount"`
+}
+
+// ParseRestartPolicy accepts no, always, unless-stopped and on-failure[:N].
+func ParseRestartPolicy(policy string) (RestartPolicy, error) {
+	name, count, hasCount := strings.Cut(policy, ":")
+	switch name {
+	case "", RestartNo:
+		name = RestartNo
+	case RestartAlways, RestartUnlessStopped, RestartOnFailure:
+	default:
+		return RestartPolicy{}, fmt.Errorf("invalid restart policy %s", policy)
+	}
+
+	restartPolicy := RestartPolicy{Name: name}
+	if hasCount {
+		if name != RestartOnFailure {
+			return RestartPolicy{}, fmt.Errorf("maximum retry count cannot be used with restart policy %s", name)
+		}
+		maximum, err := strconv.Atoi(count)
+		if err != nil || maximum < 0 {
+			return RestartPolicy{}, fmt.Errorf("invalid maximum retry count %s", count)
+		}
+		restartPolicy.MaximumRetryCount = maximum
+	}
+	return restartPolicy, nil
+}
+
+func (p RestartPolicy) String() string {
+	if p.Name == "" {
+		return RestartNo
+	}
+	if p.Name == RestartOnFailure && p.MaximumRetryCount > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaximumRetryCount)
+	}
+	return p.Name
+}
+
+// ShouldRestart decides from the recorded exit whether the supervisor has to
+// relaunch the container. A container stopped by the user is never restarted,
+// without a daemon always and unless-stopped only differ across host reboots.
+func ShouldRestart(containerInfo *Info) bool {
+	if containerInfo.Status != EXIT || containerInfo.Config == nil {
+		return false
+	}
+	policy := containerInfo.Config.RestartPolicy
+	switch policy.Name {
+	case RestartAlways, RestartUnlessStopped:
+		return true
+	case RestartOnFailure:
+		if containerInfo.ExitCode == 0 {
+			return false
+		}
+		return policy.MaximumRetryCount == 0 || containerInfo.RestartCount < policy.MaximumRetryCount
+	}
+	return false
+}
+
+// ErrRestartCanceled is returned by RestartContainerByPolicy when the
+// container was stopped, started or removed while the supervisor waited.
+var ErrRestartCanceled = errors.New("restart canceled")
+
+// RestartContainerByPolicy relaunches an exited container and counts it. The
+// state stays locked from the check to the launch so a concurrent stop either
+// cancels the restart or finds the container running.
+func RestartContainerByPolicy(containerInfo *Info, stdio *Stdio) (*exec.Cmd, Info, error) {
+	unlock, err := lockContainerInfo(containerInfo.Name)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	defer unlock()
+
+	current, err := readContainerInfo(containerInfo.Name)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	if current.Status != EXIT || current.ManuallyStopped {
+		return nil, Info{}, ErrRestartCanceled
+	}
+	current.RestartCount++
+	return StartContainer(current, stdio)
+}
+
+// LockSupervisor marks the caller as the supervisor of the container for as
+// long as the returned file stays open.
+func LockSupervisor(containerName string) (*os.File, error) {
+	lockFile, err := os.OpenFile(fmt.Sprintf(DefaultInfoLocation, containerName)+SupervisorLockFile, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = lockFile.Close()
+		return nil, fmt.Errorf("container %s already has a supervisor", containerName)
+	}
+	return lockFile, nil
+}
+
+// hasSupervisor reports whether a shim holds the supervisor lock.
+func hasSupervisor(containerName string) bool {
+	lockFile, err := os.Open(fmt.Sprintf(DefaultInfoLocation, containerName) + SupervisorLockFile)
+	if err != nil {
+		return false
+	}
+	defer lockFile.Close()
+	if err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return err == syscall.EWOULDBLOCK
+	}
+	_ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	return false
+}
@@ -0,0 +1,156 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"minidocker/cgroups"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContainerStats is one sample of the resource usage of a container.
+type ContainerStats struct {
+	Id            string    `json:"id"`
+	Name          string    `json:"name"`
+	Read          time.Time `json:"read"`
+	CPUPercent    float64   `json:"cpuPercent"`
+	MemoryUsage   uint64    `json:"memoryUsage"`
+	MemoryLimit   uint64    `json:"memoryLimit"`
+	MemoryPercent float64   `json:"memoryPercent"`
+	NetRx         uint64    `json:"netRx"`
+	NetTx         uint64    `json:"netTx"`
+	BlockRead     uint64    `json:"blockRead"`
+	BlockWrite    uint64    `json:"blockWrite"`
+	Pids          uint64    `json:"pids"`
+
+	cpuUsage    uint64
+	systemUsage uint64
+}
+
+// GetContainerStats samples the cgroup counters and network devices of a
+// running container. The cpu percentage is computed against previous, the
+// earlier sample of the same container, and left at 0 without one.
+func GetContainerStats(containerInfo *Info, previous *ContainerStats) (*ContainerStats, error) {
+	if containerInfo.Status != RUNNING && containerInfo.Status != PAUSED {
+		return nil, fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
+
+	cgroupStats, err := cgroups.NewCgroupManager(containerInfo.CgroupPath).GetStats()
+	if err != nil {
+		return nil, fmt.Errorf("read container %s cgroup stats error %s", containerInfo.Name, err)
+	}
+	systemUsage, cpus, err := readSystemCpuUsage()
+	if err != nil {
+		return nil, fmt.Errorf("read system cpu usage error %s", err)
+	}
+
+	stats := &ContainerStats{
+		Id:          containerInfo.Id,
+		Name:        containerInfo.Name,
+		Read:        time.Now(),
+		MemoryUsage: cgroupStats.MemoryUsage,
+		MemoryLimit: cgroupStats.MemoryLimit,
+		BlockRead:   cgroupStats.BlockRead,
+		BlockWrite:  cgroupStats.BlockWrite,
+		Pids:        cgroupStats.Pids,
+		cpuUsage:    cgroupStats.CpuUsage,
+		systemUsage: systemUsage,
+	}
+	if total, err := readHostMemoryTotal(); err == nil && (stats.MemoryLimit == 0 || stats.MemoryLimit > total) {
+		stats.MemoryLimit = total
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+	if previous != nil && systemUsage > previous.systemUsage && stats.cpuUsage >= previous.cpuUsage {
+		cpuDelta := float64(stats.cpuUsage - previous.cpuUsage)
+		systemDelta := float64(systemUsage - previous.systemUsage)
+		stats.CPUPercent = cpuDelta / systemDelta * float64(cpus) * 100
+	}
+
+	// the devices of the container netns are visible through its init process
+	if pid, err := strconv.Atoi(containerInfo.Pid); err == nil {
+		stats.NetRx, stats.NetTx, _ = readNetworkStats(pid)
+	}
+	return stats, nil
+}
+
+// readSystemCpuUsage returns the busy and idle time of all cpus from
+// /proc/stat in nanoseconds, and the number of cpus.
+func readSystemCpuUsage() (uint64, int, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var total uint64
+	var cpus int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cpus++
+			continue
+		}
+		for _, field := range fields[1:] {
+			ticks, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid cpu line in /proc/stat")
+			}
+			total += ticks
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	return total * uint64(time.Second) / clockTicks, cpus, nil
+}
+
+func readHostMemoryTotal() (uint64, error) {
+	content, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// MemTotal:       16314088 kB
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			total, err := strconv.ParseUint(fields[1], 10, 64)
+			return total * 1024, err
+		}
+	}
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
+
+// readNetworkStats sums the received and transmitted bytes of every device
+// but loopback in /proc/<pid>/net/dev.
+func readNetworkStats(pid int) (uint64, uint64, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/dev", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	var rx, tx uint64
+	for _, line := range strings.Split(string(content), "\n") {
+		// iface: rx_bytes packets errs drop fifo frame compressed multicast tx_bytes ...
+		device, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(device) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		received, _ := strconv.ParseUint(fields[0], 10, 64)
+		transmitted, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += received
+		tx += transmitted
+	}
+	return rx, tx, nil
+}
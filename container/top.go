@@ -0,0 +1,117 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"minidocker/cgroups"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clockTicks is USER_HZ, the unit of the cpu times in /proc/<pid>/stat.
+const clockTicks = 100
+
+// ProcessInfo describes one process of a container as seen from the host.
+type ProcessInfo struct {
+	Pid     int
+	NSpid   int
+	Uid     uint32
+	User    string
+	CPUTime uint64
+	RSS     uint64
+	Command string
+}
+
+// ListContainerProcesses reads the member processes of the container cgroup,
+// ordered by pid.
+func ListContainerProcesses(containerName string) ([]*ProcessInfo, error) {
+	containerInfo, err := ResolveContainer(containerName)
+	if err != nil {
+		return nil, err
+	}
+	if containerInfo.Status != RUNNING && containerInfo.Status != PAUSED {
+		return nil, fmt.Errorf("container %s is not running", containerInfo.Name)
+	}
+
+	pids, err := cgroups.NewCgroupManager(containerInfo.CgroupPath).GetPids()
+	if err != nil {
+		return nil, fmt.Errorf("read container processes error %s", err)
+	}
+	sort.Ints(pids)
+
+	var processes []*ProcessInfo
+	for _, pid := range pids {
+		process, err := readProcessInfo(pid)
+		if err != nil {
+			// the process exited after the procs list was read
+			continue
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+func readProcessInfo(pid int) (*ProcessInfo, error) {
+	process := &ProcessInfo{Pid: pid, NSpid: pid}
+
+	fields, err := readProcessStat(pid)
+	if err != nil {
+		return nil, err
+	}
+	// utime and stime are fields 14 and 15 of stat, the slice starts at field 3
+	if len(fields) < 13 {
+		return nil, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	process.CPUTime = (utime + stime) / clockTicks
+
+	status, err := readProcessStatus(pid)
+	if err != nil {
+		return nil, err
+	}
+	if nspid := strings.Fields(status["NSpid"]); len(nspid) > 0 {
+		// the last entry is the pid in the innermost namespace
+		process.NSpid, _ = strconv.Atoi(nspid[len(nspid)-1])
+	}
+	if uid := strings.Fields(status["Uid"]); len(uid) > 0 {
+		id, _ := strconv.ParseUint(uid[0], 10, 32)
+		process.Uid = uint32(id)
+	}
+	if rss := strings.Fields(status["VmRSS"]); len(rss) > 0 {
+		process.RSS, _ = strconv.ParseUint(rss[0], 10, 64)
+	}
+	process.User = lookupUserName(fmt.Sprintf("/proc/%d/root/etc/passwd", pid), process.Uid)
+
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, err
+	}
+	process.Command = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	if process.Command == "" {
+		// kernel threads and zombies have no arguments, ps shows the name
+		process.Command = "[" + status["Name"] + "]"
+	}
+	return process, nil
+}
+
+// readProcessStatus returns the key: value lines of /proc/<pid>/status.
+func readProcessStatus(pid int) (map[string]string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	status := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), ":"); ok {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+	return status, scanner.Err()
+}
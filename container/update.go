@@ -0,0 +1,106 @@
+package container
+
+import (
+	"fmt"
+	"minidocker/cgroups"
+	"minidocker/cgroups/subsystems"
+)
+
+// UpdateContainer changes the resource limits of a container. A running
+// container gets them applied to its cgroup right away, they are persisted
+// either way so the next start and restart use them too.
+func UpdateContainer(containerName string, res *subsystems.ResourceConfig) error {
+	containerInfo, err := ResolveContainer(containerName)
+	if err != nil {
+		return err
+	}
+
+	resource := &subsystems.ResourceConfig{}
+	if containerInfo.Config != nil && containerInfo.Config.Resource != nil {
+		*resource = *containerInfo.Config.Resource
+	}
+	mergeResource(resource, res)
+	if err = subsystems.ValidateMemory(resource); err != nil {
+		return err
+	}
+
+	if containerInfo.Status == RUNNING || containerInfo.Status == PAUSED {
+		// only the changed values are written, the others are already in place,
+		// except the memory limit and swap which are derived from each other
+		apply := *res
+		if res.MemoryLimit != "" || res.MemorySwap != "" {
+			apply.MemoryLimit = resource.MemoryLimit
+			apply.MemorySwap = resource.MemorySwap
+		}
+		if err = cgroups.NewCgroupManager(containerInfo.CgroupPath).Set(&apply); err != nil {
+			return fmt.Errorf("update container %s resources error %s", containerInfo.Name, err)
+		}
+	}
+
+	_, err = updateContainerInfo(containerInfo.Name, func(info *Info) {
+		if info.Config == nil {
+			info.Config = &Config{ContainerName: info.Name}
+		}
+		info.Config.Resource = resource
+	})
+	return err
+}
+
+// mergeResource copies the values set in update over resource.
+func mergeResource(resource *subsystems.ResourceConfig, update *subsystems.ResourceConfig) {
+	if update.MemoryLimit != "" {
+		resource.MemoryLimit = update.MemoryLimit
+	}
+	if update.MemorySwap != "" {
+		resource.MemorySwap = update.MemorySwap
+	}
+	if update.MemoryReservation != "" {
+		resource.MemoryReservation = update.MemoryReservation
+	}
+	if update.OomKillDisable != nil {
+		resource.OomKillDisable = update.OomKillDisable
+	}
+	if update.MemorySwappiness != "" {
+		resource.MemorySwappiness = update.MemorySwappiness
+	}
+	if update.CpuShare != "" {
+		resource.CpuShare = update.CpuShare
+	}
+	if update.CpuSet != "" {
+		resource.CpuSet = update.CpuSet
+	}
+	if update.CpuPeriod != "" {
+		resource.CpuPeriod = update.CpuPeriod
+	}
+	if update.CpuQuota != "" {
+		resource.CpuQuota = update.CpuQuota
+	}
+	if update.PidsLimit != "" {
+		resource.PidsLimit = update.PidsLimit
+	}
+	if update.BlkioWeight != "" {
+		resource.BlkioWeight = update.BlkioWeight
+	}
+	resource.DeviceReadBps = mergeThrottleDevices(resource.DeviceReadBps, update.DeviceReadBps)
+	resource.DeviceWriteBps = mergeThrottleDevices(resource.DeviceWriteBps, update.DeviceWriteBps)
+	resource.DeviceReadIOps = mergeThrottleDevices(resource.DeviceReadIOps, update.DeviceReadIOps)
+	resource.DeviceWriteIOps = mergeThrottleDevices(resource.DeviceWriteIOps, update.DeviceWriteIOps)
+}
+
+// mergeThrottleDevices replaces the limit of devices already throttled and
+// appends the others, a device updated to rate 0 is dropped.
+func mergeThrottleDevices(devices []subsystems.ThrottleDevice, update []subsystems.ThrottleDevice) []subsystems.ThrottleDevice {
+	for _, device := range update {
+		merged := devices[:0:0]
+		for _, existing := range devices {
+			if existing.Major != device.Major || existing.Minor != device.Minor {
+				merged = append(merged, existing)
+			}
+		}
+		if device.Rate > 0 {
+			merged = append(merged, device)
+		}
+		devices = merged
+	}
+	return devices
+}
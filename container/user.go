@@ -0,0 +1,84 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LookupUser resolves user[:group] against /etc/passwd and /etc/group of the
+// current root, numeric ids are accepted without an entry.
+func LookupUser(spec string) (uint32, uint32, error) {
+	userName, groupName, hasGroup := strings.Cut(spec, ":")
+
+	uid, gid, err := lookupID("/etc/passwd", userName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to find user %s: %v", userName, err)
+	}
+	if hasGroup {
+		if gid, _, err = lookupID("/etc/group", groupName); err != nil {
+			return 0, 0, fmt.Errorf("unable to find group %s: %v", groupName, err)
+		}
+	}
+	return uid, gid, nil
+}
+
+// lookupID returns the id and, for passwd, the primary group of name.
+func lookupID(file string, name string) (uint32, uint32, error) {
+	number, numeric := strconv.ParseUint(name, 10, 32)
+
+	f, err := os.Open(file)
+	if err != nil {
+		if numeric == nil {
+			return uint32(number), uint32(number), nil
+		}
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:password:id:gid:...
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || (fields[0] != name && fields[2] != name) {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		gid := id
+		if len(fields) > 3 {
+			if primary, err := strconv.ParseUint(fields[3], 10, 32); err == nil {
+				gid = primary
+			}
+		}
+		return uint32(id), uint32(gid), nil
+	}
+	if numeric == nil {
+		return uint32(number), uint32(number), nil
+	}
+	return 0, 0, fmt.Errorf("no matching entries in %s", file)
+}
+
+// lookupUserName returns the name of uid in the passwd file, or the id
+// itself when there is no entry.
+func lookupUserName(file string, uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	f, err := os.Open(file)
+	if err != nil {
+		return id
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) >= 3 && fields[2] == id {
+			return fields[0]
+		}
+	}
+	return id
+}
@@ -2,8 +2,10 @@ package container
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -23,6 +25,29 @@ func NewWorkSpace(volume string, containerName string, imageName string) {
 	}
 }
 
+// ReuseWorkSpace mounts the existing write layer of a stopped container again,
+// it is a no-op for mounts that are still in place.
+func ReuseWorkSpace(volume string, containerName string, imageName string) {
+	CreateReadOnlyLayer(imageName)
+	mntURL := fmt.Sprintf(MntURL, containerName)
+	if !IsMountPoint(mntURL) {
+		if err := CreateMountPoint(containerName, imageName); err != nil {
+			logger.Errorf("mount write layer error %s", err)
+		}
+	}
+
+	if len(volume) > 0 {
+		volumeURLs := volumeUrlExtract(volume)
+		if len(volumeURLs) == 2 && volumeURLs[0] != "" && volumeURLs[1] != "" {
+			if !IsMountPoint(mntURL + "/" + volumeURLs[1]) {
+				MountVolume(volumeURLs, containerName)
+			}
+		} else {
+			logger.Errorln("mount volume error")
+		}
+	}
+}
+
 func CreateReadOnlyLayer(imageName string) {
 	unTarFolderURL := RootURL + "/" + imageName + "/"
 	imageURL := RootURL + "/" + imageName + ".tar"
@@ -136,6 +161,21 @@ func PathExist(path string) (bool, error) {
 	return false, err
 }
 
+func IsMountPoint(path string) bool {
+	content, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Split(line, " ")
+		if len(fields) > 4 && fields[4] == path {
+			return true
+		}
+	}
+	return false
+}
+
 func volumeUrlExtract(volume string) []string {
 	return strings.Split(volume, ":")
 }
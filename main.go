@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"minidocker/cmd"
+	"os"
 	"runtime/debug"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
+		var statusErr *cmd.StatusError
+		if errors.As(err, &statusErr) {
+			os.Exit(statusErr.StatusCode)
+		}
 		fmt.Println(err)
 		fmt.Println(string(debug.Stack()))
 	}
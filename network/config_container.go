@@ -39,6 +39,8 @@ func configEndpointIPAddressAndRouter(ep *Endpoint, info *container.Info) error
 	return nil
 }
 
+// configPortMapping adds a DNAT rule per published port and records it in
+// the endpoint, Disconnect deletes them again by the same spec.
 func configPortMapping(ep *Endpoint, info *container.Info) error {
 	for _, port := range ep.PortMapping {
 		mapping := strings.Split(port, ":")
@@ -47,11 +49,31 @@ func configPortMapping(ep *Endpoint, info *container.Info) error {
 			continue
 		}
 
-		command := fmt.Sprintf("-t nat -A PREROUTING -p tcp -m tcp --dport %s -j DNAT --to-destination %s:%s", mapping[0], ep.IPAddress.String(), mapping[1])
-		if _, err := exec.Command("iptables", strings.Split(command, " ")...).CombinedOutput(); err != nil {
+		rule := fmt.Sprintf("PREROUTING -p tcp -m tcp --dport %s -j DNAT --to-destination %s:%s", mapping[0], ep.IPAddress.String(), mapping[1])
+		if err := execNatRule("-A", rule); err != nil {
 			logger.Errorf("exec iptables error %s", err)
 			continue
 		}
+		ep.PortRules = append(ep.PortRules, rule)
+	}
+	return nil
+}
+
+// removePortMapping deletes the DNAT rules added for the endpoint, a stale
+// rule would keep forwarding the host port once the address is reused.
+func removePortMapping(ep *Endpoint) {
+	for _, rule := range ep.PortRules {
+		if err := execNatRule("-D", rule); err != nil {
+			logger.Errorf("delete iptables rule %s error %s", rule, err)
+		}
+	}
+	ep.PortRules = nil
+}
+
+func execNatRule(action string, rule string) error {
+	args := append([]string{"-t", "nat", action}, strings.Split(rule, " ")...)
+	if output, err := exec.Command("iptables", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s", err, strings.TrimSpace(string(output)))
 	}
 	return nil
 }
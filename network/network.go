@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"io/fs"
+	"io/ioutil"
 	"minidocker/container"
 	"net"
 	"os"
@@ -16,10 +17,11 @@ import (
 )
 
 var (
-	defaultNetworkPath = "/var/run/minidocker/network/network"
-	drivers            = map[string]Driver{}
-	networks           = map[string]*Network{}
-	logger             = zap.NewExample().Sugar()
+	defaultNetworkPath  = "/var/run/minidocker/network/network"
+	defaultEndpointPath = "/var/run/minidocker/network/endpoint"
+	drivers             = map[string]Driver{}
+	networks            = map[string]*Network{}
+	logger              = zap.NewExample().Sugar()
 )
 
 type Network struct {
@@ -34,6 +36,7 @@ type Endpoint struct {
 	IPAddress   net.IP           `json:"ip"`
 	MacAddress  net.HardwareAddr `json:"mac"`
 	PortMapping []string         `json:"portMapping"`
+	PortRules   []string         `json:"portRules"`
 	Network     *Network
 }
 
@@ -102,6 +105,33 @@ func (n *Network) remove(dumpPath string) error {
 	return os.Remove(path.Join(dumpPath, n.Name))
 }
 
+func (ep *Endpoint) dump(dumpPath string) error {
+	if err := os.MkdirAll(dumpPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ep)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(dumpPath, ep.ID), data, 0644)
+}
+
+func (ep *Endpoint) load(dumpPath string) error {
+	data, err := ioutil.ReadFile(dumpPath)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, ep)
+}
+
+func (ep *Endpoint) remove(dumpPath string) error {
+	if err := os.Remove(path.Join(dumpPath, ep.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func Init() error {
 	var bridgeDriver = BridgeNetworkDriver{}
 	drivers[bridgeDriver.Name()] = &bridgeDriver
@@ -200,9 +230,69 @@ func Connect(name string, info *container.Info) error {
 	if err = configEndpointIPAddressAndRouter(ep, info); err != nil {
 		return err
 	}
-	return configPortMapping(ep, info)
+	if err = configPortMapping(ep, info); err != nil {
+		return err
+	}
+	return ep.dump(defaultEndpointPath)
 }
 
+// Disconnect deletes the port mapping rules and releases the address of the
+// container endpoint, the veth pair itself disappears together with the
+// container netns.
 func Disconnect(name string, info *container.Info) error {
-	return nil
+	ep, err := GetEndpoint(info.Id, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	removePortMapping(ep)
+	if network, ok := networks[name]; ok {
+		if err = ipAllocator.Release(network.IPRange, &ep.IPAddress); err != nil {
+			logger.Errorf("release endpoint ip error %s", err)
+		}
+	}
+	return ep.remove(defaultEndpointPath)
+}
+
+func GetNetwork(name string) (*Network, error) {
+	network, ok := networks[name]
+	if !ok {
+		return nil, fmt.Errorf("no such network %s", name)
+	}
+	return network, nil
+}
+
+func GetEndpoint(containerId string, name string) (*Endpoint, error) {
+	ep := &Endpoint{}
+	if err := ep.load(path.Join(defaultEndpointPath, fmt.Sprintf("%s-%s", containerId, name))); err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+// ListEndpoints returns every endpoint attached to the network.
+func ListEndpoints(name string) ([]*Endpoint, error) {
+	files, err := ioutil.ReadDir(defaultEndpointPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var endpoints []*Endpoint
+	for _, file := range files {
+		ep := &Endpoint{}
+		if err := ep.load(path.Join(defaultEndpointPath, file.Name())); err != nil {
+			logger.Errorf("load endpoint error %s", err)
+			continue
+		}
+		if ep.Network != nil && ep.Network.Name == name {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints, nil
 }
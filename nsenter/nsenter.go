@@ -9,51 +9,159 @@ package nsenter
 #include <fcntl.h>
 #include <sched.h>
 #include <errno.h>
+#include <sys/wait.h>
+#include <sys/stat.h>
 
-__attribute__((constructor)) void enter_namespace(void)
+#define MAX_CGROUPS 16
+
+// open_cgroups opens cgroup.procs of every directory listed in
+// minidocker_cgroup while the host cgroupfs is still reachable.
+static int open_cgroups(int *fds)
 {
-    char *docker_pid = getenv("minidocker_pid");
-    if (docker_pid)
+    char *cgroups = getenv("minidocker_cgroup");
+    if (!cgroups || !*cgroups)
     {
-        printf("get docker pid : %s\n", docker_pid);
+        return 0;
     }
-    else
+
+    int count = 0;
+    char *paths = strdup(cgroups);
+    char *saveptr = NULL;
+    char procs[1024];
+    for (char *dir = strtok_r(paths, ":", &saveptr); dir && count < MAX_CGROUPS; dir = strtok_r(NULL, ":", &saveptr))
     {
-        printf("missing docker pid");
-        return;
+        snprintf(procs, sizeof(procs), "%s/cgroup.procs", dir);
+        fds[count] = open(procs, O_WRONLY | O_CLOEXEC);
+        if (fds[count] == -1)
+        {
+            fprintf(stderr, "open cgroup %s failed : %s\n", dir, strerror(errno));
+            exit(1);
+        }
+        count++;
     }
+    free(paths);
+    return count;
+}
 
-    char *docker_cmd = getenv("minidocker_cmd");
-    if (docker_cmd)
+// open_cgroup_namespace returns the cgroup namespace of the container, or
+// -1 when it shares ours, joining the one we already are in is rejected.
+static int open_cgroup_namespace(char *docker_pid)
+{
+    char nspath[1024];
+    struct stat self, target;
+    snprintf(nspath, sizeof(nspath), "/proc/%s/ns/cgroup", docker_pid);
+    if (stat(nspath, &target) == -1 || stat("/proc/self/ns/cgroup", &self) == -1)
     {
-        printf("get docker cmd : %s\n", docker_cmd);
+        return -1;
     }
-    else
+    if (self.st_dev == target.st_dev && self.st_ino == target.st_ino)
+    {
+        return -1;
+    }
+
+    int fd = open(nspath, O_RDONLY | O_CLOEXEC);
+    if (fd == -1)
     {
-        printf("missing docker cmd");
+        fprintf(stderr, "open cgroup namespace of container process %s failed : %s\n", docker_pid, strerror(errno));
+        exit(1);
+    }
+    return fd;
+}
+
+// join_cgroups moves the calling process, "0" stands for the writer, into
+// the opened cgroups and then into the cgroup namespace of the container.
+static void join_cgroups(int *fds, int count, int cgroupns)
+{
+    for (int i = 0; i < count; i++)
+    {
+        if (write(fds[i], "0", 1) == -1)
+        {
+            fprintf(stderr, "join cgroup failed : %s\n", strerror(errno));
+            exit(1);
+        }
+        close(fds[i]);
+    }
+    if (cgroupns != -1)
+    {
+        if (setns(cgroupns, CLONE_NEWCGROUP) == -1)
+        {
+            fprintf(stderr, "setns on cgroup namespace failed : %s\n", strerror(errno));
+            exit(1);
+        }
+        close(cgroupns);
+    }
+}
+
+__attribute__((constructor)) void enter_namespace(void)
+{
+    char *docker_pid = getenv("minidocker_pid");
+    if (!docker_pid)
+    {
+        // not an exec, leave the process where it is
         return;
     }
 
+    int cgroups[MAX_CGROUPS];
+    int cgroup_count = open_cgroups(cgroups);
+    int cgroupns = open_cgroup_namespace(docker_pid);
+
+    // open every namespace before joining any, the mnt namespace hides the
+    // host /proc and a missing container must not leave us half entered
     char nspath[1024];
     char *namespaces[] = {"ipc", "uts", "net", "pid", "mnt"};
+    int fds[5];
     for (size_t i = 0; i < 5; i++)
     {
-        sprintf(nspath, "/proc/%s/ns/%s", docker_pid, namespaces[i]);
-        int fd = open(nspath, O_RDONLY);
-        if (setns(fd, 0) == -1)
+        snprintf(nspath, sizeof(nspath), "/proc/%s/ns/%s", docker_pid, namespaces[i]);
+        fds[i] = open(nspath, O_RDONLY);
+        if (fds[i] == -1)
         {
-            printf("setns on %s failed : %s\n", namespaces[i], strerror(errno));
+            fprintf(stderr, "open %s namespace of container process %s failed : %s\n", namespaces[i], docker_pid, strerror(errno));
+            exit(1);
         }
-        else
+    }
+    for (size_t i = 0; i < 5; i++)
+    {
+        if (setns(fds[i], 0) == -1)
         {
-            printf("setns on %s succeeded\n", namespaces[i]);
+            fprintf(stderr, "setns on %s namespace failed : %s\n", namespaces[i], strerror(errno));
+            exit(1);
         }
-        close(fd);
+        close(fds[i]);
+    }
+
+    // a process can not start threads after joining another pid namespace,
+    // fork so the go runtime starts as a member of it and wait on its behalf,
+    // only the child joins the cgroups so the waiter is not accounted
+    pid_t child = fork();
+    if (child < 0)
+    {
+        fprintf(stderr, "fork failed : %s\n", strerror(errno));
+        exit(1);
+    }
+    if (child == 0)
+    {
+        join_cgroups(cgroups, cgroup_count, cgroupns);
+        return;
+    }
+    for (int i = 0; i < cgroup_count; i++)
+    {
+        close(cgroups[i]);
+    }
+    if (cgroupns != -1)
+    {
+        close(cgroupns);
     }
 
-    int res = system(docker_cmd);
-	exit(0);
-    return;
+    int status;
+    while (waitpid(child, &status, 0) < 0 && errno == EINTR)
+    {
+    }
+    if (WIFSIGNALED(status))
+    {
+        exit(128 + WTERMSIG(status));
+    }
+    exit(WEXITSTATUS(status));
 }
 */
 import "C"
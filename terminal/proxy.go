@@ -0,0 +1,37 @@
+package terminal
+
+import (
+	"io"
+	"os"
+)
+
+// Proxy puts the host terminal in raw mode and copies the container console
+// to stdout, and stdin to the console when input is set. The returned func
+// waits for the remaining output once the container side is closed and
+// restores the host terminal.
+func Proxy(console *os.File, input bool) func() {
+	restore := func() {}
+	if state, err := MakeRaw(os.Stdin.Fd()); err == nil {
+		restore = func() { _ = Restore(os.Stdin.Fd(), state) }
+	}
+	stopResize := ResizeOnWinch(os.Stdin, console)
+
+	if input {
+		go func() {
+			_, _ = io.Copy(console, os.Stdin)
+		}()
+	}
+	outputDone := make(chan struct{})
+	go func() {
+		// the read fails with EIO once every slave fd is closed
+		_, _ = io.Copy(os.Stdout, console)
+		close(outputDone)
+	}()
+
+	return func() {
+		<-outputDone
+		stopResize()
+		restore()
+		_ = console.Close()
+	}
+}